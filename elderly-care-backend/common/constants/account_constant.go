@@ -1,5 +1,71 @@
 package constants
 
+import "time"
+
 const (
 	PHONE_REGIX = "^1[3-9][0-9]{9}$"
 )
+
+// 账号角色
+const (
+	ROLE_ELDERLY   = "elderly"   // 老人
+	ROLE_VOLUNTEER = "volunteer" // 志愿者
+	ROLE_GUARDIAN  = "guardian"  // 监护人/家属
+	ROLE_ADMIN     = "admin"     // 管理员
+	ROLE_OPERATOR  = "operator"  // 运营人员，只能查看管理后台
+)
+
+// token 类型，写入 jwt 的 subject
+const (
+	TOKEN_SUBJECT_ACCESS  = "login"
+	TOKEN_SUBJECT_REFRESH = "refresh"
+)
+
+// 登录方式，写入登录审计日志
+const (
+	LOGIN_METHOD_PASSWORD = "password"
+	LOGIN_METHOD_SMS      = "sms"
+)
+
+// 登录防暴力破解：前几次失败不限制，之后每次失败的等待时间翻倍，失败次数达到上限后锁定
+const (
+	LOGIN_FAIL_WINDOW        = time.Hour        // 最后一次失败后计数保留的时长
+	LOGIN_FREE_ATTEMPTS      = 3                // 不需要等待的失败次数
+	LOGIN_BACKOFF_BASE       = 2 * time.Second  // 第一次退避等待时间
+	LOGIN_BACKOFF_MAX        = 5 * time.Minute  // 单次退避等待上限
+	LOGIN_PHONE_MAX_FAILURES = 10               // 同一手机号失败次数上限
+	LOGIN_IP_MAX_FAILURES    = 50               // 同一IP失败次数上限
+	LOGIN_LOCK_DURATION      = 30 * time.Minute // 锁定时长
+)
+
+// 密码规则和找回密码
+const (
+	PASSWORD_MIN_LENGTH      = 8
+	PASSWORD_MAX_LENGTH      = 32
+	PASSWORD_RESET_TOKEN_TTL = 10 * time.Minute // 验证手机号后设置新密码的时限
+)
+
+// 旧版本注册时使用的角色，等同于老人
+const ROLE_LEGACY_USER = "user"
+
+// 账号状态
+const (
+	ACCOUNT_STATUS_ACTIVE    = "active"
+	ACCOUNT_STATUS_SUSPENDED = "suspended"
+	ACCOUNT_STATUS_DELETED   = "deleted" // 已注销，个人信息已匿名化
+)
+
+// 注销申请状态
+const (
+	DELETION_STATUS_PENDING   = "pending"   // 冷静期内，可以撤销
+	DELETION_STATUS_CANCELLED = "cancelled" // 用户已撤销
+	DELETION_STATUS_COMPLETED = "completed" // 已执行匿名化
+)
+
+// 注销和个人数据导出
+const (
+	ACCOUNT_DELETION_GRACE_PERIOD  = 7 * 24 * time.Hour // 申请注销后的冷静期
+	ACCOUNT_DELETION_SCAN_INTERVAL = 10 * time.Minute   // 扫描到期注销申请的间隔
+	ACCOUNT_EXPORT_LINK_TTL        = 24 * time.Hour     // 导出数据中私有文件临时链接的有效期
+	ACCOUNT_DELETED_NICKNAME       = "已注销用户"
+)
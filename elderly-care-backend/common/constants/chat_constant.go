@@ -0,0 +1,32 @@
+package constants
+
+const (
+	CHAT_SYNC_LIMIT     = 200 // 重连补发和同步单次最多返回的消息数
+	CHAT_PREVIEW_LENGTH = 30  // 会话列表消息预览的最大字数
+	CHAT_PREVIEW_IMAGE  = "[图片]"
+	CHAT_PREVIEW_FILE   = "[文件]"
+	CHAT_PREVIEW_VOICE  = "[语音]"
+	CHAT_PREVIEW_RECALL = "[消息已撤回]"
+
+	CHAT_DEFAULT_RECALL_WINDOW = 120 // 默认撤回时限(秒)
+
+	CHAT_TEXT_MAX_LENGTH = 2000 // 文本消息最大字数
+	CHAT_URL_MAX_LENGTH  = 512  // 图片、文件和语音消息地址的最大长度
+
+	CHAT_VOICE_MIN_DURATION = 1        // 语音最短时长(秒)
+	CHAT_VOICE_MAX_DURATION = 60       // 语音最长时长(秒)
+	CHAT_VOICE_MAX_SIZE     = 5 << 20  // 语音文件最大字节数
+	CHAT_VOICE_DIR          = "voice/" // 语音在文件桶中的目录
+
+	CHAT_GROUP_MAX_MEMBERS = 50
+	CHAT_GROUP_TASK_PREFIX = "任务：" // 任务群名称前缀
+)
+
+// 群成员角色和状态
+const (
+	CHAT_GROUP_ROLE_OWNER  = "owner"
+	CHAT_GROUP_ROLE_MEMBER = "member"
+
+	CHAT_GROUP_MEMBER_INVITED = "invited"
+	CHAT_GROUP_MEMBER_JOINED  = "joined"
+)
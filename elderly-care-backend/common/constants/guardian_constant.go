@@ -0,0 +1,8 @@
+package constants
+
+// 监护关系状态
+const (
+	GUARDIAN_STATUS_PENDING  = "pending"  // 等待监护人确认
+	GUARDIAN_STATUS_VERIFIED = "verified" // 已确认
+	GUARDIAN_STATUS_REJECTED = "rejected" // 监护人拒绝
+)
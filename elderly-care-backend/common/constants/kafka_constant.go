@@ -8,4 +8,7 @@ const (
 
 	MESSAGE_TOPIC = "message"
 	MESSAGE_GROUP = "message_group"
+
+	REALTIME_EVENT_TOPIC        = "realtime_event"
+	REALTIME_EVENT_GROUP_PREFIX = "realtime_event_group_" // 每个节点单独一个消费组，都能收到全部事件
 )
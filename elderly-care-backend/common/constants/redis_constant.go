@@ -13,10 +13,42 @@ const (
 	//锁
 	LOCK_MUSIC_WEEK_RANK         = "lock:music_week_rank"
 	LOCK_MUSIC_SOURCE_AND_LYRICS = "lock:source_lyrics"
+	LOCK_SOS_ESCALATION          = "lock:sos_escalation"
+	LOCK_TASK_EXPIRY             = "lock:task_expiry"
+	LOCK_ACCOUNT_DELETION        = "lock:account_deletion"
 
 	//redis计数器
 	CHAT_ID_COUNT = "chat_id_count"
 
 	//账户信息
 	ACCOUNT_LOGINTYPE = "account:login_type"
+
+	//登录会话：token版本号变化后旧token全部失效，刷新token只能使用一次
+	ACCOUNT_TOKEN_VERSION_PREFIX = "account:token_version:"
+	ACCOUNT_REFRESH_TOKEN_PREFIX = "account:refresh_token:"
+	ACCOUNT_REVOKED_TOKEN_PREFIX = "account:revoked_token:"
+	ACCOUNT_RESET_TOKEN_PREFIX   = "account:reset_token:" // 找回密码的重置凭证，只能使用一次
+
+	//登录失败计数、退避和锁定，后缀为 phone:<手机号> 或 ip:<IP>
+	LOGIN_FAIL_PREFIX    = "login:fail:"
+	LOGIN_BACKOFF_PREFIX = "login:backoff:"
+	LOGIN_LOCK_PREFIX    = "login:lock:"
+
+	//短信验证码及发送频率限制
+	SMS_CODE_PREFIX        = "sms:code:"        // sms:code:<用途>:<手机号>
+	SMS_COOLDOWN_PREFIX    = "sms:cooldown:"    // 重发间隔
+	SMS_PHONE_DAILY_PREFIX = "sms:phone_daily:" // 手机号每日发送次数
+	SMS_IP_HOURLY_PREFIX   = "sms:ip_hourly:"   // IP每小时发送次数
+
+	//离线推送事件队列，用户上线后补发
+	WS_OFFLINE_EVENTS_PREFIX = "ws:offline_events:"
+	WS_OFFLINE_EVENTS_TTL    = 24 * time.Hour
+
+	//多实例推送：节点之间转发事件的频道，以及用户连接所在的节点
+	WS_BROKER_CHANNEL  = "ws:broker"
+	WS_PRESENCE_PREFIX = "ws:presence:" // hash，field 为节点ID，值为在线状态的过期时间戳
+
+	//语音上传记录，发送语音消息时据此取服务端计算的时长，值为 <上传人ID>:<时长>
+	CHAT_VOICE_PREFIX = "chat:voice:"
+	CHAT_VOICE_TTL    = 24 * time.Hour
 )
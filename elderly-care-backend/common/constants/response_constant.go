@@ -14,6 +14,20 @@ const (
 	NOT_LOGIN               = "NOT LOGIN"
 	INVALID_TOKEN           = "INVALID TOKEN"
 	PASSWORD_ERROR          = "PASSWORD ERROR"
+	ACCOUNT_SUSPENDED       = "ACCOUNT SUSPENDED"
+	ROLE_INVALID            = "ROLE INVALID"
+	REFRESH_TOKEN_INVALID   = "REFRESH TOKEN INVALID"
+	TOKEN_REVOKED           = "TOKEN REVOKED"
+	ACCOUNT_LOCKED          = "ACCOUNT LOCKED"
+	LOGIN_TOO_FREQUENT      = "LOGIN TOO FREQUENT"
+	PASSWORD_TOO_WEAK       = "PASSWORD TOO WEAK"
+	RESET_TOKEN_INVALID     = "RESET TOKEN INVALID"
+
+	// 短信验证码
+	SMS_CODE_INVALID   = "SMS CODE INVALID"
+	SMS_TOO_FREQUENT   = "SMS TOO FREQUENT"
+	SMS_LIMIT_EXCEEDED = "SMS LIMIT EXCEEDED"
+	SMS_SEND_ERROR     = "SMS SEND ERROR"
 
 	// FILE
 	UPLOAD_ERROR      = "UPLOAD ERROR"
@@ -30,11 +44,15 @@ const (
 	MUSIC_NOT_EXIST = "MUSIC NOT EXISTS"
 
 	// TASK 相关错误
-	TASK_NOT_EXIST        = "TASK NOT EXISTS"
-	TASK_ALREADY_ACCEPTED = "TASK ALREADY ACCEPTED"
-	DISTANCE_TOO_FAR      = "DISTANCE TOO FAR"
-	LOCATION_REQUIRED     = "LOCATION REQUIRED"
-	TASK_CREATE_FAILED    = "TASK CREATE FAILED"
+	TASK_NOT_EXIST          = "TASK NOT EXISTS"
+	TASK_ALREADY_ACCEPTED   = "TASK ALREADY ACCEPTED"
+	DISTANCE_TOO_FAR        = "DISTANCE TOO FAR"
+	LOCATION_REQUIRED       = "LOCATION REQUIRED"
+	TASK_CREATE_FAILED      = "TASK CREATE FAILED"
+	TASK_INVALID_TRANSITION = "TASK INVALID TRANSITION"
+	TASK_NOT_ASSIGNEE       = "TASK NOT ASSIGNEE"
+	TASK_NOT_CREATOR        = "TASK NOT CREATOR"
+	TASK_DEADLINE_INVALID   = "TASK DEADLINE INVALID"
 
 	// SOS 相关错误
 	SOS_NOT_EXIST          = "SOS NOT EXISTS"
@@ -47,5 +65,55 @@ const (
 	FILE_UPLOAD_ERROR = "FILE UPLOAD ERROR"
 
 	//评价相关
-	EVALUATION_ERROR = "EVALUATION ERROR"
+	EVALUATION_ERROR       = "EVALUATION ERROR"
+	EVALUATION_EXISTS      = "EVALUATION EXISTS"
+	EVALUATION_NOT_ALLOWED = "EVALUATION NOT ALLOWED"
+	TASK_NOT_COMPLETED     = "TASK NOT COMPLETED"
+
+	// 志愿者相关
+	AVAILABILITY_WINDOW_ERROR       = "AVAILABILITY WINDOW ERROR"
+	VERIFICATION_NOT_EXIST          = "VERIFICATION NOT EXISTS"
+	VERIFICATION_INVALID_TRANSITION = "VERIFICATION INVALID TRANSITION"
+
+	// 监护人相关
+	GUARDIAN_NOT_EXIST     = "GUARDIAN NOT EXISTS"
+	GUARDIAN_EXIST         = "GUARDIAN EXISTS"
+	GUARDIAN_SELF          = "CANNOT GUARD SELF"
+	GUARDIAN_ROLE_REQUIRED = "GUARDIAN ROLE REQUIRED"
+	GUARDIAN_NOT_PENDING   = "GUARDIAN INVITATION NOT PENDING"
+
+	// 实时推送相关
+	TOPIC_INVALID = "TOPIC INVALID"
+	FRAME_INVALID = "FRAME INVALID"
+
+	// 注销相关
+	DELETION_NOT_EXIST         = "DELETION NOT EXISTS"
+	DELETION_ALREADY_REQUESTED = "DELETION ALREADY REQUESTED"
+	ACCOUNT_HAS_OPEN_TASKS     = "ACCOUNT HAS OPEN TASKS"
+
+	// 群聊相关
+	CHAT_GROUP_NOT_EXIST   = "CHAT GROUP NOT EXISTS"
+	CHAT_GROUP_NOT_MEMBER  = "NOT CHAT GROUP MEMBER"
+	CHAT_GROUP_NOT_OWNER   = "NOT CHAT GROUP OWNER"
+	CHAT_GROUP_NOT_INVITED = "NOT INVITED TO CHAT GROUP"
+	CHAT_GROUP_FULL        = "CHAT GROUP FULL"
+
+	// 语音消息
+	VOICE_TOO_LARGE    = "VOICE TOO LARGE"
+	VOICE_TOO_SHORT    = "VOICE TOO SHORT"
+	VOICE_TOO_LONG     = "VOICE TOO LONG"
+	VOICE_NOT_UPLOADED = "VOICE NOT UPLOADED"
+
+	// 消息撤回、编辑和删除
+	MESSAGE_NOT_EXIST      = "MESSAGE NOT EXISTS"
+	MESSAGE_NOT_SENDER     = "NOT MESSAGE SENDER"
+	MESSAGE_RECALLED       = "MESSAGE RECALLED"
+	MESSAGE_RECALL_EXPIRED = "MESSAGE RECALL EXPIRED"
+	MESSAGE_NOT_EDITABLE   = "MESSAGE NOT EDITABLE"
+
+	// 发送消息校验
+	MESSAGE_TYPE_INVALID     = "MESSAGE TYPE INVALID"
+	MESSAGE_TOO_LONG         = "MESSAGE TOO LONG"
+	MESSAGE_URL_INVALID      = "MESSAGE URL INVALID"
+	CHAT_RECIPIENT_NOT_EXIST = "CHAT RECIPIENT NOT EXISTS"
 )
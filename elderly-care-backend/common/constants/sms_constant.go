@@ -0,0 +1,18 @@
+package constants
+
+// 短信验证码用途，不同用途的验证码互不通用
+const (
+	SMS_PURPOSE_LOGIN        = "login"          // 验证码登录
+	SMS_PURPOSE_REGISTER     = "register"       // 注册时验证手机号
+	SMS_PURPOSE_VERIFY_PHONE = "verify_phone"   // 已登录用户补充验证手机号
+	SMS_PURPOSE_RESET        = "reset_password" // 忘记密码
+)
+
+// 未配置时使用的默认值
+const (
+	SMS_DEFAULT_CODE_TTL        = 300
+	SMS_DEFAULT_RESEND_INTERVAL = 60
+	SMS_DEFAULT_PHONE_DAILY_MAX = 10
+	SMS_DEFAULT_IP_HOURLY_MAX   = 30
+	SMS_DEFAULT_MAX_ATTEMPTS    = 5
+)
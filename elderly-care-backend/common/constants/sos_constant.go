@@ -0,0 +1,19 @@
+package constants
+
+import "time"
+
+// SOS 状态
+const (
+	SOS_STATUS_PENDING     = "pending"
+	SOS_STATUS_MATCHING    = "matching"
+	SOS_STATUS_ACCEPTED    = "accepted"
+	SOS_STATUS_IN_PROGRESS = "in_progress"
+	SOS_STATUS_ESCALATED   = "escalated" // 自动升级结束，等待人工跟进
+	SOS_STATUS_RESOLVED    = "resolved"
+)
+
+// SOS 超时升级
+const (
+	SOS_RESPONSE_TIMEOUT         = 5 * time.Minute  // 首次匹配后等待响应的时间
+	SOS_ESCALATION_SCAN_INTERVAL = 30 * time.Second // 升级任务扫描间隔
+)
@@ -0,0 +1,17 @@
+package constants
+
+import "time"
+
+// 任务状态
+const (
+	TASK_STATUS_PENDING     = "pending"
+	TASK_STATUS_ASSIGNED    = "assigned"
+	TASK_STATUS_IN_PROGRESS = "in_progress"
+	TASK_STATUS_COMPLETED   = "completed"
+	TASK_STATUS_CANCELLED   = "cancelled"
+	TASK_STATUS_EXPIRED     = "expired"
+)
+
+const (
+	TASK_EXPIRY_SCAN_INTERVAL = time.Minute // 过期任务扫描间隔
+)
@@ -0,0 +1,9 @@
+package constants
+
+// 志愿者认证状态
+const (
+	VERIFICATION_STATUS_PENDING   = "pending"   // 已提交材料，等待审核
+	VERIFICATION_STATUS_VERIFIED  = "verified"  // 审核通过，可以接单
+	VERIFICATION_STATUS_REJECTED  = "rejected"  // 审核驳回，可重新提交
+	VERIFICATION_STATUS_SUSPENDED = "suspended" // 认证被暂停，不能接单
+)
@@ -0,0 +1,77 @@
+package constants
+
+import "time"
+
+// WebSocket 推送事件类型
+const (
+	WS_EVENT_SOS_ALERT          = "sos_alert"
+	WS_EVENT_SOS_GUARDIAN_ALERT = "sos_guardian_alert" // 发给监护人，先于志愿者
+	WS_EVENT_SOS_ACCEPTED       = "sos_accepted"
+	WS_EVENT_SOS_RESOLVED       = "sos_resolved"
+	WS_EVENT_TASK_EXPIRED       = "task_expired"
+	WS_EVENT_TASK_STATUS        = "task_status_changed" // 发布到任务主题
+	WS_EVENT_LOCATION_UPDATED   = "location_updated"    // 只推送给有权限查看位置的用户
+	WS_EVENT_NAVIGATION_RESULT  = "navigation_response"
+	WS_EVENT_CHAT_MESSAGE       = "chat_message"
+	WS_EVENT_CHAT_RECEIPT       = "chat_receipt" // 送达/已读回执，发给消息发送方和确认方的其他设备
+	WS_EVENT_CHAT_GROUP_INVITED = "chat_group_invited"
+	WS_EVENT_CHAT_RECALLED      = "chat_recalled" // 撤回和编辑推送给消息的所有接收方
+	WS_EVENT_CHAT_EDITED        = "chat_edited"
+	WS_EVENT_CHAT_DELETED       = "chat_deleted" // 删除只对自己生效，只同步给自己的其他设备
+)
+
+// 网关帧的业务通道，写入帧的 type 字段，事件名以通道名开头
+const (
+	WS_CHANNEL_CHAT       = "chat"
+	WS_CHANNEL_LOCATION   = "location"
+	WS_CHANNEL_SOS        = "sos"
+	WS_CHANNEL_TASK       = "task"
+	WS_CHANNEL_NAVIGATION = "navigation"
+)
+
+// 网关连接参数的默认值
+const (
+	WS_DEFAULT_PING_INTERVAL    = 30        // 心跳间隔(秒)
+	WS_DEFAULT_PONG_TIMEOUT     = 75        // 超过该时间没有收到任何数据断开连接(秒)
+	WS_DEFAULT_MAX_MESSAGE_SIZE = 64 * 1024 // 单个帧最大字节数
+	WS_WRITE_TIMEOUT            = 10 * time.Second
+)
+
+// WebSocket 订阅主题类型，主题格式为 <类型>:<ID>，如 task:12
+const (
+	WS_TOPIC_USER       = "user" // 个人事件，只能订阅自己的，连接建立后自动订阅
+	WS_TOPIC_TASK       = "task"
+	WS_TOPIC_SOS        = "sos"
+	WS_TOPIC_NAVIGATION = "navigation"
+)
+
+// WebSocket 控制帧类型
+const (
+	WS_FRAME_SUBSCRIBE    = "subscribe"
+	WS_FRAME_UNSUBSCRIBE  = "unsubscribe"
+	WS_FRAME_SUBSCRIBED   = "subscribed"
+	WS_FRAME_UNSUBSCRIBED = "unsubscribed"
+	WS_FRAME_ERROR        = "error"
+	WS_FRAME_PING         = "ping" // 浏览器无法发送协议层 ping 时使用
+	WS_FRAME_PONG         = "pong"
+)
+
+// 客户端发来的聊天帧类型，发送消息使用 chat
+const (
+	WS_FRAME_CHAT_RECEIPT = "chat_receipt" // 确认对方发来的消息已送达或已读
+	WS_FRAME_CHAT_SYNC    = "chat_sync"    // 拉取 last_message_id 之后的消息
+	WS_FRAME_CHAT_RECALL  = "chat_recall"  // 发送方在时限内撤回消息
+	WS_FRAME_CHAT_EDIT    = "chat_edit"    // 发送方编辑文本消息
+	WS_FRAME_CHAT_DELETE  = "chat_delete"  // 在自己这一侧删除消息
+)
+
+// 多实例部署时节点之间转发推送事件的消息通道
+const (
+	WS_BROKER_REDIS  = "redis" // 默认，Redis 发布订阅
+	WS_BROKER_KAFKA  = "kafka"
+	WS_BROKER_MEMORY = "memory" // 只在同一进程内转发，用于单实例部署和本地测试
+
+	WS_PRESENCE_TTL              = 90 * time.Second // 节点宕机后其上的在线状态最多保留的时间
+	WS_PRESENCE_REFRESH_INTERVAL = 30 * time.Second
+	WS_BROKER_RETRY_INTERVAL     = 3 * time.Second // 订阅断开后重连的间隔
+)
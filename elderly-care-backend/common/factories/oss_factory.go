@@ -9,12 +9,14 @@ import (
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"io"
 	"log"
+	"time"
 )
 
 const (
 	MUSIC_SOURCE_BUCKET   = "music-source"
 	ACCOUNT_AVATAR_BUCKET = "account-avatar"
 	FILE_BUCKET           = "file"
+	VERIFICATION_BUCKET   = "volunteer-verification" // 志愿者证件，私有桶，只能通过管理员接口读取
 )
 
 type OssType int
@@ -78,6 +80,9 @@ type OssClient interface {
 	Download(bucketName string, objectName string) ([]byte, error)
 	GetServiceUrl() string
 	GetRawClient() interface{}
+	// PresignedUrl 生成私有对象的临时下载链接
+	PresignedUrl(bucketName string, objectName string, expiry time.Duration) (string, error)
+	Remove(bucketName string, objectName string) error
 }
 
 type MiniOssClient minio.Client
@@ -116,6 +121,20 @@ func (client *MiniOssClient) GetServiceUrl() string {
 	return fmt.Sprintf("%s://%s", endPoint.Scheme, endPoint.Host)
 }
 
+func (client *MiniOssClient) PresignedUrl(bucketName string, objectName string, expiry time.Duration) (string, error) {
+	minioClient := (*minio.Client)(client)
+	u, err := minioClient.PresignedGetObject(context.Background(), bucketName, objectName, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (client *MiniOssClient) Remove(bucketName string, objectName string) error {
+	minioClient := (*minio.Client)(client)
+	return minioClient.RemoveObject(context.Background(), bucketName, objectName, minio.RemoveObjectOptions{})
+}
+
 // 获取原生客户端
 func (client *MiniOssClient) GetRawClient() interface{} {
 	return (*minio.Client)(client)
@@ -0,0 +1,64 @@
+package factories
+
+import (
+	"elderly-care-backend/config"
+	. "elderly-care-backend/global"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	SMS_PROVIDER_LOG = "log"
+)
+
+// SmsSender 短信发送接口，接入新的短信服务商时实现该接口并注册到工厂
+type SmsSender interface {
+	Send(phone string, content string) error
+}
+
+type SmsFactory struct {
+	senderMap map[string]SmsSender
+}
+
+var SmsSenderFactory *SmsFactory
+
+func InitSmsFactory() {
+	smsFactory := &SmsFactory{
+		senderMap: make(map[string]SmsSender),
+	}
+	smsFactory.Register(SMS_PROVIDER_LOG, &LogSmsSender{})
+	SmsSenderFactory = smsFactory
+}
+
+// Register 注册短信服务商
+func (this *SmsFactory) Register(provider string, sender SmsSender) {
+	this.senderMap[provider] = sender
+}
+
+// GetSmsSender 获取配置的短信服务商，未配置或不存在时退回日志发送
+func (this *SmsFactory) GetSmsSender() SmsSender {
+	if sender, ok := this.senderMap[config.Config.Sms.Provider]; ok {
+		return sender
+	}
+	Logger.Warn("sms provider not found, fallback to log", zap.String("provider", config.Config.Sms.Provider))
+	return this.senderMap[SMS_PROVIDER_LOG]
+}
+
+// 短信里连续4位以上的数字视为验证码
+var smsCodePattern = regexp.MustCompile(`\d{4,}`)
+
+// LogSmsSender 只把短信内容写入日志，用于本地开发和离线测试，验证码打码后再写入
+type LogSmsSender struct{}
+
+func (*LogSmsSender) Send(phone string, content string) error {
+	Logger.Info("sms sent", zap.String("phone", phone), zap.String("content", maskSmsCode(content)))
+	return nil
+}
+
+func maskSmsCode(content string) string {
+	return smsCodePattern.ReplaceAllStringFunc(content, func(code string) string {
+		return strings.Repeat("*", len(code))
+	})
+}
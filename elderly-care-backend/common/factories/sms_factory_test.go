@@ -0,0 +1,38 @@
+package factories
+
+import (
+	"elderly-care-backend/config"
+	"elderly-care-backend/global"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogSmsSenderMasksCode(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger, appConfig := global.Logger, config.Config
+	global.Logger = zap.New(core)
+	config.Config = &config.AppConfig{}
+	t.Cleanup(func() { global.Logger, config.Config = logger, appConfig })
+
+	config.Config.Sms.Provider = SMS_PROVIDER_LOG
+	InitSmsFactory()
+	content := "【颐养】您的验证码为483920，5分钟内有效"
+	if err := SmsSenderFactory.GetSmsSender().Send("13800138000", content); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	entries := logs.FilterMessage("sms sent").All()
+	if len(entries) != 1 {
+		t.Fatalf("sms log entries = %d, want 1", len(entries))
+	}
+	logged := entries[0].ContextMap()["content"].(string)
+	if strings.Contains(logged, "483920") {
+		t.Errorf("code leaked into log: %s", logged)
+	}
+	if want := "【颐养】您的验证码为******，5分钟内有效"; logged != want {
+		t.Errorf("content = %q, want %q", logged, want)
+	}
+}
@@ -0,0 +1,14 @@
+package server_error
+
+import (
+	"elderly-care-backend/common/constants"
+	"errors"
+)
+
+// 任务状态流转错误，错误信息即返回给前端的错误码
+var (
+	TaskNotExistError          = errors.New(constants.TASK_NOT_EXIST)
+	TaskInvalidTransitionError = errors.New(constants.TASK_INVALID_TRANSITION)
+	TaskNotAssigneeError       = errors.New(constants.TASK_NOT_ASSIGNEE)
+	TaskNotCreatorError        = errors.New(constants.TASK_NOT_CREATOR)
+)
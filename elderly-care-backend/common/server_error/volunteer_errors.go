@@ -0,0 +1,12 @@
+package server_error
+
+import (
+	"elderly-care-backend/common/constants"
+	"errors"
+)
+
+// 志愿者认证错误，错误信息即返回给前端的错误码
+var (
+	VerificationNotExistError          = errors.New(constants.VERIFICATION_NOT_EXIST)
+	VerificationInvalidTransitionError = errors.New(constants.VERIFICATION_INVALID_TRANSITION)
+)
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -53,6 +54,34 @@ type AppConfig struct {
 		Expire        int    `mapstructure:"expire"`
 		RefreshExpire int    `mapstructure:"refresh_expire"`
 	}
+	// 短信验证码
+	Sms struct {
+		Provider       string `mapstructure:"provider"`        // 短信服务商，log 表示只写日志不真正发送
+		SignName       string `mapstructure:"sign_name"`       // 短信签名
+		CodeTTL        int    `mapstructure:"code_ttl"`        // 验证码有效期(秒)
+		ResendInterval int    `mapstructure:"resend_interval"` // 同一手机号重发间隔(秒)
+		PhoneDailyMax  int    `mapstructure:"phone_daily_max"` // 同一手机号每天最多发送次数
+		IPHourlyMax    int    `mapstructure:"ip_hourly_max"`   // 同一IP每小时最多发送次数
+		MaxAttempts    int    `mapstructure:"max_attempts"`    // 同一验证码最多校验次数
+	} `mapstructure:"sms"`
+	// 志愿者匹配打分
+	Matching struct {
+		MaxDistance float64            `mapstructure:"max_distance"` // 匹配半径(米)
+		Weights     map[string]float64 `mapstructure:"weights"`      // 各匹配因子的权重
+	} `mapstructure:"matching"`
+	// 实时推送网关(/ws)
+	Realtime struct {
+		AllowedOrigins []string `mapstructure:"allowed_origins"`  // 允许建立连接的页面来源，* 表示不限制；没有 Origin 的原生客户端不受限制
+		PingInterval   int      `mapstructure:"ping_interval"`    // 心跳间隔(秒)
+		PongTimeout    int      `mapstructure:"pong_timeout"`     // 超过该时间没有收到任何数据断开连接(秒)
+		MaxMessageSize int64    `mapstructure:"max_message_size"` // 单个帧最大字节数
+		Broker         string   `mapstructure:"broker"`           // 节点之间转发事件的通道：redis(默认)、kafka、memory
+		NodeID         string   `mapstructure:"node_id"`          // 节点标识，默认为主机名，同一台机器上的多个实例需分别配置
+	} `mapstructure:"realtime"`
+	// 聊天
+	Chat struct {
+		RecallWindow int `mapstructure:"recall_window"` // 发送后多久内可以撤回(秒)
+	} `mapstructure:"chat"`
 	// 新增 Map 配置
 	Map struct {
 		AMap struct {
@@ -161,6 +190,22 @@ func overrideConfigWithEnv() {
 		}
 	}
 
+	// 短信配置
+	if smsProvider := os.Getenv("SMS_PROVIDER"); smsProvider != "" {
+		Config.Sms.Provider = smsProvider
+	}
+
+	// 实时推送网关允许的来源，逗号分隔
+	if origins := os.Getenv("REALTIME_ALLOWED_ORIGINS"); origins != "" {
+		Config.Realtime.AllowedOrigins = strings.Split(origins, ",")
+	}
+	if broker := os.Getenv("REALTIME_BROKER"); broker != "" {
+		Config.Realtime.Broker = broker
+	}
+	if nodeID := os.Getenv("REALTIME_NODE_ID"); nodeID != "" {
+		Config.Realtime.NodeID = nodeID
+	}
+
 	// 高德地图配置
 	if amapKey := os.Getenv("AMAP_API_KEY"); amapKey != "" {
 		Config.Map.AMap.APIKey = amapKey
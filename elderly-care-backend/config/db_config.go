@@ -1,6 +1,7 @@
 package config
 
 import (
+	"elderly-care-backend/common/constants"
 	"elderly-care-backend/global"
 	"elderly-care-backend/models"
 
@@ -73,9 +74,22 @@ func initDB() {
 	modelsList := []interface{}{
 		&models.Account{},
 		&models.Message{},
+		&models.MessageDeletion{},
 		&models.AccountEvaluation{},
 		&models.ContactList{},
 		&models.UserLocation{}, // 添加用户位置表
+		&models.SOSEscalationLog{},
+		&models.Guardian{},
+		&models.TaskStatusHistory{},
+		&models.TaskEvaluation{},
+		&models.VolunteerAvailability{},
+		&models.AvailabilityWindow{},
+		&models.VolunteerVerification{},
+		&models.LoginAttempt{},
+		&models.AccountDeletion{},
+		&models.NavigationSession{},
+		&models.ChatGroup{},
+		&models.ChatGroupMember{},
 		//&models.Task{},
 		//&models.SOSRecord{},
 	}
@@ -86,6 +100,13 @@ func initDB() {
 		return
 	}
 
+	// 旧版本注册的 user 角色即老人
+	if err = db.Model(&models.Account{}).Where("role = ?", constants.ROLE_LEGACY_USER).
+		Update("role", constants.ROLE_ELDERLY).Error; err != nil {
+		log.Fatalf("账号角色迁移失败: %v", err)
+		return
+	}
+
 	fmt.Fprintf(logFile, "数据库初始化成功\n")
 	log.Println("数据库初始化和迁移完成")
 }
@@ -19,6 +19,11 @@ func initKafka() {
 			NumPartitions:     2,
 			ReplicationFactor: 1,
 		},
+		{
+			Topic:             constants.REALTIME_EVENT_TOPIC,
+			NumPartitions:     1,
+			ReplicationFactor: 1,
+		},
 	}
 	err = conn.CreateTopics(kafkaTopics...)
 	if err != nil {
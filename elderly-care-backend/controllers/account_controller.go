@@ -3,26 +3,42 @@ package controllers
 import (
 	"elderly-care-backend/common/constants"
 	"elderly-care-backend/common/factories"
-	"elderly-care-backend/config"
 	"elderly-care-backend/dto/account_dto"
 	. "elderly-care-backend/global"
 	"elderly-care-backend/models"
+	"elderly-care-backend/services"
 	"elderly-care-backend/utils"
 	"elderly-care-backend/vo"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"regexp"
 	"strconv"
-	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type AccountController struct {
+	tokenService         *services.TokenService
+	smsCodeService       *services.SmsCodeService
+	loginGuardService    *services.LoginGuardService
+	passwordResetService *services.PasswordResetService
+	exportService        *services.AccountExportService
+	deletionService      *services.AccountDeletionService
+}
+
+func NewAccountController() *AccountController {
+	return &AccountController{
+		tokenService:         services.NewTokenService(Db, RedisClient),
+		smsCodeService:       services.NewSmsCodeService(RedisClient),
+		loginGuardService:    services.NewLoginGuardService(Db, RedisClient),
+		passwordResetService: services.NewPasswordResetService(Db, RedisClient),
+		exportService:        services.NewAccountExportService(Db),
+		deletionService:      services.NewAccountDeletionService(Db, RedisClient),
+	}
 }
 
 // @Tags 账号模块
@@ -36,7 +52,8 @@ type AccountController struct {
 // @Param avatar formData file true "头像"
 // @Param sex formData int true "性别(男性:0,女性:1)"
 // @Param age formData int true "年龄"
-// @Param role formData string true "角色(user , admin)"
+// @Param role formData string true "角色(elderly, volunteer, guardian)"
+// @Param sms_code formData string false "注册验证码，填写后手机号标记为已验证"
 // @Success 200 {object} vo.ResponseVO "成功"
 // @Failure 400 {object} vo.ResponseVO "错误"
 // @Router /account/register [post]
@@ -59,6 +76,27 @@ func (this *AccountController) Register(c *gin.Context) {
 		return
 	}
 
+	// 管理员和运营账号只能由后台创建，不能自行注册
+	switch registerDTO.Role {
+	case "", constants.ROLE_LEGACY_USER:
+		registerDTO.Role = constants.ROLE_ELDERLY
+	case constants.ROLE_ELDERLY, constants.ROLE_VOLUNTEER, constants.ROLE_GUARDIAN:
+	default:
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.ROLE_INVALID))
+		return
+	}
+
+	// 填写了验证码时校验手机号归属
+	phoneVerified := false
+	if registerDTO.SmsCode != "" {
+		if err = this.smsCodeService.VerifyCode(c.Request.Context(), registerDTO.Phone,
+			constants.SMS_PURPOSE_REGISTER, registerDTO.SmsCode); err != nil {
+			smsError(c, err)
+			return
+		}
+		phoneVerified = true
+	}
+
 	client := factories.OssClientFactory.GetOssClient(factories.MINIO)
 	avatar := registerDTO.Avatar
 
@@ -89,6 +127,8 @@ func (this *AccountController) Register(c *gin.Context) {
 			Avatar:   url,
 			Age:      registerDTO.Age,
 			Role:     registerDTO.Role,
+
+			PhoneVerified: phoneVerified,
 		}
 
 		if err = tx.Create(account).Error; err != nil {
@@ -120,21 +160,13 @@ func (this *AccountController) Register(c *gin.Context) {
 // @Produce json
 // @Param  phone query string true "手机号"
 // @Param  password query string true "密码"
-// @Success 200 {object} vo.ResponseVO "成功"
+// @Success 200 {object} vo.ResponseVO{data=vo.TokenVO} "成功"
 // @Failure 400 {object} vo.ResponseVO "失败"
 // @Router /account/login [post]
 func (this *AccountController) Login(c *gin.Context) {
 	phone := c.Query("phone")
 	password := c.Query("password")
 
-	// === 只添加这一行 ===
-	if phone == "13811112222" {
-		newHash, _ := utils.HashPassword("123456")
-		Db.Model(&models.Account{}).Where("phone = ?", phone).Update("password", newHash)
-		fmt.Printf("密码已重置为123456\n")
-	}
-	// === 结束添加 ===
-
 	// 检查必需参数
 	if phone == "" || password == "" {
 		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
@@ -147,8 +179,18 @@ func (this *AccountController) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, vo.Fail(constants.PHONE_FOAMAT_ERROR))
 		return
 	}
+
+	// 手机号或IP失败次数过多时先退避等待，达到上限后锁定
+	ctx := c.Request.Context()
+	attempt := loginAttemptInfo(c, phone, constants.LOGIN_METHOD_PASSWORD)
+	if err := this.loginGuardService.Check(ctx, phone, attempt.IP); err != nil {
+		loginBlocked(c, err)
+		return
+	}
+
 	if err := Db.Where("phone = ?", phone).Take(account).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			this.loginGuardService.RecordFailure(ctx, attempt, nil, constants.PHONE_OR_PASSWORD_ERROR)
 			c.JSON(http.StatusBadRequest, vo.Fail(constants.PHONE_OR_PASSWORD_ERROR))
 			return
 		} else {
@@ -156,22 +198,334 @@ func (this *AccountController) Login(c *gin.Context) {
 			return
 		}
 	}
+	if !utils.CheckPasswordHash(password, account.Password) {
+		this.loginGuardService.RecordFailure(ctx, attempt, &account.ID, constants.PHONE_OR_PASSWORD_ERROR)
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PHONE_OR_PASSWORD_ERROR))
+		return
+	}
+	this.completeLogin(c, attempt, account)
+}
 
-	accessClaims := &account_dto.Claims{
-		AccountId: account.ID, // 现在类型匹配了
-		Nickname:  account.Nickname,
-		StandardClaims: jwt.StandardClaims{
-			Issuer:    "elder",
-			Subject:   "login",
-			ExpiresAt: time.Now().Add(time.Duration(config.Config.Jwt.Expire) * time.Second).Unix(),
-		},
+// 凭证校验通过后的处理：检查账号状态、写审计日志并签发token
+func (this *AccountController) completeLogin(c *gin.Context, attempt services.LoginAttemptInfo, account *models.Account) {
+	ctx := c.Request.Context()
+	if account.Status == constants.ACCOUNT_STATUS_SUSPENDED {
+		this.loginGuardService.RecordFailure(ctx, attempt, &account.ID, constants.ACCOUNT_SUSPENDED)
+		c.JSON(http.StatusForbidden, vo.Fail(constants.ACCOUNT_SUSPENDED))
+		return
 	}
-	accessToken, err := utils.GenToken(accessClaims, config.Config.Jwt.SecretKey)
+	this.loginGuardService.RecordSuccess(ctx, attempt, account.ID)
+
+	tokens, err := this.tokenService.IssueTokens(ctx, account)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, vo.Fail(constants.SERVICE_ERROR))
 		return
 	}
-	c.JSON(http.StatusOK, vo.Success(accessToken))
+	c.JSON(http.StatusOK, vo.Success(tokens))
+}
+
+func loginAttemptInfo(c *gin.Context, phone, method string) services.LoginAttemptInfo {
+	return services.LoginAttemptInfo{
+		Phone:     phone,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Method:    method,
+	}
+}
+
+// 登录被锁定或需要等待时返回错误码和剩余秒数
+func loginBlocked(c *gin.Context, err error) {
+	var blocked *services.LoginBlockedError
+	if !errors.As(err, &blocked) {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	retryAfter := int(math.Ceil(blocked.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, &vo.ResponseVO{
+		Success: false,
+		Data:    vo.LoginBlockedVO{RetryAfter: retryAfter},
+		Msg:     blocked.Code,
+	})
+}
+
+// @Tags 账号模块
+// @Summary 发送短信验证码
+// @Description 发送登录、注册、验证手机号或找回密码用的验证码，同一手机号60秒内只能发送一次，并限制每日次数和单个IP的发送频率
+// @Accept json
+// @Produce json
+// @Param request body account_dto.SmsCodeDTO true "手机号和用途"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/sms/code [post]
+func (this *AccountController) SendSmsCode(c *gin.Context) {
+	dto := &account_dto.SmsCodeDTO{}
+	if err := c.ShouldBindJSON(dto); err != nil || !services.IsValidSmsPurpose(dto.Purpose) {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+	if ok, _ := regexp.MatchString(constants.PHONE_REGIX, dto.Phone); !ok {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PHONE_FOAMAT_ERROR))
+		return
+	}
+
+	// 注册要求手机号未被使用，其他用途要求手机号已注册
+	var count int64
+	if err := Db.Model(&models.Account{}).Where("phone = ?", dto.Phone).Count(&count).Error; err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	if dto.Purpose == constants.SMS_PURPOSE_REGISTER && count > 0 {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PHONE_EXIST))
+		return
+	}
+	if dto.Purpose != constants.SMS_PURPOSE_REGISTER && count == 0 {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.ACCOUNT_NOT_EXIST))
+		return
+	}
+
+	if err := this.smsCodeService.SendCode(c.Request.Context(), dto.Phone, dto.Purpose, c.ClientIP()); err != nil {
+		smsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 账号模块
+// @Summary 验证码登录
+// @Description 使用短信验证码登录，无需密码，登录成功后手机号标记为已验证
+// @Accept json
+// @Produce json
+// @Param request body account_dto.SmsLoginDTO true "手机号和验证码"
+// @Success 200 {object} vo.ResponseVO{data=vo.TokenVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/login/sms [post]
+func (this *AccountController) SmsLogin(c *gin.Context) {
+	dto := &account_dto.SmsLoginDTO{}
+	if err := c.ShouldBindJSON(dto); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	ctx := c.Request.Context()
+	attempt := loginAttemptInfo(c, dto.Phone, constants.LOGIN_METHOD_SMS)
+	if err := this.loginGuardService.Check(ctx, dto.Phone, attempt.IP); err != nil {
+		loginBlocked(c, err)
+		return
+	}
+	if err := this.smsCodeService.VerifyCode(ctx, dto.Phone, constants.SMS_PURPOSE_LOGIN, dto.Code); err != nil {
+		if errors.Is(err, services.ErrSmsCodeInvalid) {
+			this.loginGuardService.RecordFailure(ctx, attempt, nil, constants.SMS_CODE_INVALID)
+		}
+		smsError(c, err)
+		return
+	}
+
+	account := &models.Account{}
+	if err := Db.Where("phone = ?", dto.Phone).Take(account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.ACCOUNT_NOT_EXIST))
+		} else {
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return
+	}
+	if !account.PhoneVerified {
+		Db.Model(account).Update("phone_verified", true)
+	}
+	this.completeLogin(c, attempt, account)
+}
+
+// @Tags 账号模块
+// @Summary 验证手机号
+// @Description 已登录用户使用 verify_phone 验证码验证自己的手机号
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body account_dto.VerifyPhoneDTO true "验证码"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/phone/verify [post]
+func (this *AccountController) VerifyPhone(c *gin.Context) {
+	dto := &account_dto.VerifyPhoneDTO{}
+	if err := c.ShouldBindJSON(dto); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	account := &models.Account{}
+	if err := Db.Select("id", "phone").Take(account, utils.GetAccountIdInContext(c)).Error; err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	if err := this.smsCodeService.VerifyCode(c.Request.Context(), account.Phone,
+		constants.SMS_PURPOSE_VERIFY_PHONE, dto.Code); err != nil {
+		smsError(c, err)
+		return
+	}
+	if err := Db.Model(account).Update("phone_verified", true).Error; err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 账号模块
+// @Summary 找回密码-验证手机号
+// @Description 校验 reset_password 验证码，返回10分钟内有效的一次性重置凭证
+// @Accept json
+// @Produce json
+// @Param request body account_dto.ResetVerifyDTO true "手机号和验证码"
+// @Success 200 {object} vo.ResponseVO{data=vo.ResetTokenVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/password/reset/verify [post]
+func (this *AccountController) VerifyResetCode(c *gin.Context) {
+	dto := &account_dto.ResetVerifyDTO{}
+	if err := c.ShouldBindJSON(dto); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := this.smsCodeService.VerifyCode(ctx, dto.Phone, constants.SMS_PURPOSE_RESET, dto.Code); err != nil {
+		smsError(c, err)
+		return
+	}
+	account := &models.Account{}
+	if err := Db.Select("id").Where("phone = ?", dto.Phone).Take(account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.ACCOUNT_NOT_EXIST))
+		} else {
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return
+	}
+
+	resetToken, err := this.passwordResetService.IssueResetToken(ctx, account.ID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(vo.ResetTokenVO{
+		ResetToken: resetToken,
+		ExpiresIn:  int(constants.PASSWORD_RESET_TOKEN_TTL.Seconds()),
+	}))
+}
+
+// @Tags 账号模块
+// @Summary 找回密码-设置新密码
+// @Description 使用重置凭证设置新密码，成功后所有已登录的会话失效，登录锁定同时解除
+// @Accept json
+// @Produce json
+// @Param request body account_dto.ResetPasswordDTO true "重置凭证和新密码"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/password/reset [post]
+func (this *AccountController) ResetPassword(c *gin.Context) {
+	dto := &account_dto.ResetPasswordDTO{}
+	if err := c.ShouldBindJSON(dto); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	if err := this.passwordResetService.ResetWithToken(c.Request.Context(), dto.ResetToken, dto.NewPassword); err != nil {
+		passwordResetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+func passwordResetError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrResetTokenInvalid), errors.Is(err, services.ErrPasswordTooWeak):
+		c.JSON(http.StatusBadRequest, vo.Fail(err.Error()))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.ACCOUNT_NOT_EXIST))
+	default:
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+	}
+}
+
+// 短信验证码相关错误，错误信息即错误码
+func smsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSmsCodeInvalid):
+		c.JSON(http.StatusBadRequest, vo.Fail(err.Error()))
+	case errors.Is(err, services.ErrSmsTooFrequent), errors.Is(err, services.ErrSmsLimitExceeded):
+		c.JSON(http.StatusTooManyRequests, vo.Fail(err.Error()))
+	case errors.Is(err, services.ErrSmsSend):
+		c.JSON(http.StatusBadGateway, vo.Fail(err.Error()))
+	default:
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+	}
+}
+
+// @Tags 账号模块
+// @Summary 刷新token
+// @Description 使用刷新token换取新的访问/刷新token对，旧的刷新token立即作废；已作废的刷新token再次使用时吊销该账号所有会话
+// @Accept json
+// @Produce json
+// @Param request body account_dto.RefreshTokenDTO true "刷新token"
+// @Success 200 {object} vo.ResponseVO{data=vo.TokenVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/refresh [post]
+func (this *AccountController) Refresh(c *gin.Context) {
+	dto := &account_dto.RefreshTokenDTO{}
+	if err := c.ShouldBindJSON(dto); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	tokens, err := this.tokenService.Refresh(c.Request.Context(), dto.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRefreshTokenInvalid), errors.Is(err, services.ErrTokenRevoked):
+			c.JSON(http.StatusGone, vo.Fail(err.Error()))
+		case errors.Is(err, services.ErrAccountSuspended):
+			c.JSON(http.StatusForbidden, vo.Fail(constants.ACCOUNT_SUSPENDED))
+		default:
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(tokens))
+}
+
+// @Tags 账号模块
+// @Summary 退出登录
+// @Description 作废当前访问token和传入的刷新token，all 为 true 时退出所有设备
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body account_dto.LogoutDTO false "退出参数"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 500 {object} vo.ResponseVO "失败"
+// @Router /account/logout [post]
+func (this *AccountController) Logout(c *gin.Context) {
+	dto := &account_dto.LogoutDTO{}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(dto); err != nil {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	accountID := utils.GetAccountIdInContext(c)
+	var err error
+	if dto.All {
+		err = this.tokenService.RevokeAll(ctx, accountID)
+	} else {
+		err = this.tokenService.Revoke(ctx, utils.GetClaimsInContext(c))
+		if err == nil && dto.RefreshToken != "" {
+			err = this.tokenService.RevokeRefreshToken(ctx, accountID, dto.RefreshToken)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
 }
 
 // @Tags 账号模块
@@ -305,22 +659,26 @@ func (*AccountController) GetAccountInfoByAccountID(c *gin.Context) {
 // @Produce json
 // @Security ApiKeyAuth
 // @Param oldPassword query string true "原密码"
-// @Param newPassword query string true "新密码"
-// @Success 200 {object} vo.ResponseVO "成功"
+// @Param newPassword query string true "新密码，8-32位，需同时包含字母和数字"
+// @Success 200 {object} vo.ResponseVO{data=vo.TokenVO} "成功，其他设备需重新登录，当前设备使用返回的新token"
 // @Failure 500 {object} vo.ResponseVO "错误"
 // @Router /account/changePassword [put]
-func (*AccountController) ChangePassword(c *gin.Context) {
+func (this *AccountController) ChangePassword(c *gin.Context) {
 	oldPassword := c.Query("oldPassword")
 	newPassword := c.Query("newPassword")
-	var hashedPassword string
-	if err := Db.Model(&models.Account{}).Select("password").Where("id = ?", utils.GetAccountIdInContext(c)).Take(&hashedPassword).Error; err != nil {
+	current := &models.Account{}
+	if err := Db.Select("password", "phone").Where("id = ?", utils.GetAccountIdInContext(c)).Take(current).Error; err != nil {
 		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
 		return
 	}
-	if !utils.CheckPasswordHash(oldPassword, hashedPassword) {
+	if !utils.CheckPasswordHash(oldPassword, current.Password) {
 		c.JSON(http.StatusBadRequest, vo.Fail(constants.PASSWORD_ERROR))
 		return
 	}
+	if !utils.CheckPasswordStrength(newPassword, current.Phone) {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PASSWORD_TOO_WEAK))
+		return
+	}
 	hashedPassword, err := utils.HashPassword(newPassword)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
@@ -330,6 +688,133 @@ func (*AccountController) ChangePassword(c *gin.Context) {
 		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
 		return
 	}
+
+	// 修改密码后吊销所有已登录的会话，再为当前设备签发新token
+	ctx := c.Request.Context()
+	account := &models.Account{}
+	if err = Db.Take(account, utils.GetAccountIdInContext(c)).Error; err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	if err = this.tokenService.RevokeAll(ctx, account.ID); err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	tokens, err := this.tokenService.IssueTokens(ctx, account)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(tokens))
+
+}
+
+// @Tags 账号模块
+// @Summary 导出个人数据
+// @Description 下载与当前账号相关的全部数据(JSON)，头像、聊天图片和文件以链接给出，证件照为24小时有效的临时链接
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} vo.AccountExportVO "导出文件"
+// @Failure 502 {object} vo.ResponseVO "失败"
+// @Router /account/export [get]
+func (this *AccountController) ExportData(c *gin.Context) {
+	accountID := utils.GetAccountIdInContext(c)
+	export, err := this.exportService.Export(accountID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=account-%d-%s.json",
+		accountID, export.ExportedAt.Format("20060102150405")))
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// @Tags 账号模块
+// @Summary 申请注销账号
+// @Description 需要输入密码确认，7天冷静期后匿名化账号并删除位置轨迹等个人数据，冷静期内可以撤销；有未结束的任务或SOS时不能申请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body account_dto.DeletionRequestDTO true "密码和注销原因"
+// @Success 200 {object} vo.ResponseVO{data=vo.AccountDeletionVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/deletion [post]
+func (this *AccountController) RequestDeletion(c *gin.Context) {
+	dto := &account_dto.DeletionRequestDTO{}
+	if err := c.ShouldBindJSON(dto); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	accountID := utils.GetAccountIdInContext(c)
+	var hashedPassword string
+	if err := Db.Model(&models.Account{}).Select("password").Where("id = ?", accountID).Take(&hashedPassword).Error; err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	if !utils.CheckPasswordHash(dto.Password, hashedPassword) {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PASSWORD_ERROR))
+		return
+	}
+
+	deletion, err := this.deletionService.RequestDeletion(accountID, dto.Reason)
+	if err != nil {
+		deletionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(toAccountDeletionVO(deletion)))
+}
+
+// @Tags 账号模块
+// @Summary 查询注销申请
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} vo.ResponseVO{data=vo.AccountDeletionVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "没有注销申请"
+// @Router /account/deletion [get]
+func (this *AccountController) GetDeletion(c *gin.Context) {
+	deletion, err := this.deletionService.GetDeletion(utils.GetAccountIdInContext(c))
+	if err != nil {
+		deletionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(toAccountDeletionVO(deletion)))
+}
+
+// @Tags 账号模块
+// @Summary 撤销注销申请
+// @Description 冷静期内撤销注销，账号数据保持不变
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "没有待执行的注销申请"
+// @Router /account/deletion [delete]
+func (this *AccountController) CancelDeletion(c *gin.Context) {
+	if err := this.deletionService.CancelDeletion(utils.GetAccountIdInContext(c)); err != nil {
+		deletionError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, vo.Success(nil))
+}
 
+func deletionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDeletionNotExist),
+		errors.Is(err, services.ErrDeletionAlreadyRequested),
+		errors.Is(err, services.ErrAccountHasOpenTasks):
+		c.JSON(http.StatusBadRequest, vo.Fail(err.Error()))
+	default:
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+	}
+}
+
+func toAccountDeletionVO(deletion *models.AccountDeletion) vo.AccountDeletionVO {
+	return vo.AccountDeletionVO{
+		Status:      deletion.Status,
+		Reason:      deletion.Reason,
+		RequestedAt: deletion.CreatedAt,
+		ScheduledAt: deletion.ScheduledAt,
+		CancelledAt: deletion.CancelledAt,
+		CompletedAt: deletion.CompletedAt,
+	}
 }
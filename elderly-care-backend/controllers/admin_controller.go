@@ -0,0 +1,352 @@
+package controllers
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/factories"
+	"elderly-care-backend/common/server_error"
+	"elderly-care-backend/dto"
+	. "elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"elderly-care-backend/services"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AdminController struct {
+	adminService        *services.AdminService
+	verificationService *services.VolunteerVerificationService
+	loginGuardService   *services.LoginGuardService
+}
+
+func NewAdminController() *AdminController {
+	return &AdminController{
+		adminService:        services.NewAdminService(Db, services.NewTokenService(Db, RedisClient)),
+		verificationService: services.NewVolunteerVerificationService(Db),
+		loginGuardService:   services.NewLoginGuardService(Db, RedisClient),
+	}
+}
+
+// @Tags 管理模块
+// @Summary 账号列表
+// @Description 分页查询账号，可按昵称/手机号搜索，按角色和状态筛选
+// @Produce json
+// @Security ApiKeyAuth
+// @Param keyword query string false "昵称或手机号"
+// @Param role query string false "角色" Enums(elderly, volunteer, guardian, admin, operator)
+// @Param status query string false "账号状态" Enums(active, suspended)
+// @Param page query int false "页码，从1开始"
+// @Param size query int false "每页数量"
+// @Success 200 {object} vo.ResponseVO{data=vo.PageVO} "成功"
+// @Failure 403 {object} vo.ResponseVO "失败"
+// @Router /admin/accounts [get]
+func (ac *AdminController) ListAccounts(c *gin.Context) {
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	accounts, total, err := ac.adminService.SearchAccounts(services.AccountQuery{
+		Keyword: strings.TrimSpace(c.Query("keyword")),
+		Role:    c.Query("role"),
+		Status:  c.Query("status"),
+	}, page, size)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(vo.PageVO{List: accounts, Total: total}))
+}
+
+// @Tags 管理模块
+// @Summary 暂停账号
+// @Description 暂停后账号不能登录，已登录的会话立即失效，志愿者自动下岗；管理员账号不能被暂停
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param accountId path int true "账号ID"
+// @Param request body dto.SuspendAccountRequest true "暂停原因"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /admin/accounts/{accountId}/suspend [put]
+func (ac *AdminController) SuspendAccount(c *gin.Context) {
+	accountID, err := strconv.ParseUint(c.Param("accountId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.INVALID_ACCOUNT_ID))
+		return
+	}
+	var req dto.SuspendAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	if err := ac.adminService.SuspendAccount(c.Request.Context(), uint(accountID), req.Reason); err != nil {
+		ac.accountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 管理模块
+// @Summary 恢复账号
+// @Description 恢复被暂停的账号，志愿者需自行重新上岗
+// @Produce json
+// @Security ApiKeyAuth
+// @Param accountId path int true "账号ID"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /admin/accounts/{accountId}/restore [put]
+func (ac *AdminController) RestoreAccount(c *gin.Context) {
+	accountID, err := strconv.ParseUint(c.Param("accountId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.INVALID_ACCOUNT_ID))
+		return
+	}
+
+	if err := ac.adminService.RestoreAccount(uint(accountID)); err != nil {
+		ac.accountError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 管理模块
+// @Summary 解除登录锁定
+// @Description 清除账号手机号的登录失败计数和锁定，IP维度的锁定不受影响
+// @Produce json
+// @Security ApiKeyAuth
+// @Param accountId path int true "账号ID"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /admin/accounts/{accountId}/unlock [put]
+func (ac *AdminController) UnlockAccount(c *gin.Context) {
+	accountID, err := strconv.ParseUint(c.Param("accountId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.INVALID_ACCOUNT_ID))
+		return
+	}
+
+	account := &models.Account{}
+	if err = Db.Select("id", "phone").Take(account, accountID).Error; err != nil {
+		ac.accountError(c, err)
+		return
+	}
+	if err = ac.loginGuardService.Unlock(c.Request.Context(), account.Phone); err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 管理模块
+// @Summary 登录审计日志
+// @Description 分页查询登录记录，包含成功和失败的尝试，可按手机号筛选
+// @Produce json
+// @Security ApiKeyAuth
+// @Param phone query string false "手机号"
+// @Param page query int false "页码，从1开始"
+// @Param size query int false "每页数量"
+// @Success 200 {object} vo.ResponseVO{data=vo.PageVO} "成功"
+// @Failure 403 {object} vo.ResponseVO "失败"
+// @Router /admin/login-attempts [get]
+func (ac *AdminController) ListLoginAttempts(c *gin.Context) {
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	attempts, total, err := ac.loginGuardService.ListAttempts(c.Query("phone"), page, size)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(vo.PageVO{List: attempts, Total: total}))
+}
+
+// @Tags 管理模块
+// @Summary 任务列表
+// @Description 分页查询任务，不指定状态时返回所有未结束的任务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query string false "任务状态"
+// @Param page query int false "页码，从1开始"
+// @Param size query int false "每页数量"
+// @Success 200 {object} vo.ResponseVO{data=vo.PageVO} "成功"
+// @Failure 403 {object} vo.ResponseVO "失败"
+// @Router /admin/tasks [get]
+func (ac *AdminController) ListTasks(c *gin.Context) {
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	tasks, total, err := ac.adminService.ListTasks(c.Query("status"), page, size)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(vo.PageVO{List: tasks, Total: total}))
+}
+
+// @Tags 管理模块
+// @Summary SOS列表
+// @Description 分页查询SOS记录，不指定状态时返回所有未解决的记录
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query string false "SOS状态"
+// @Param page query int false "页码，从1开始"
+// @Param size query int false "每页数量"
+// @Success 200 {object} vo.ResponseVO{data=vo.PageVO} "成功"
+// @Failure 403 {object} vo.ResponseVO "失败"
+// @Router /admin/sos [get]
+func (ac *AdminController) ListSOS(c *gin.Context) {
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	records, total, err := ac.adminService.ListSOS(c.Query("status"), page, size)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(vo.PageVO{List: records, Total: total}))
+}
+
+// @Tags 管理模块
+// @Summary 志愿者认证列表
+// @Description 按状态分页查询志愿者认证申请，默认按提交时间先后排序
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query string false "认证状态" Enums(pending, verified, rejected, suspended)
+// @Param page query int false "页码，从1开始"
+// @Param size query int false "每页数量"
+// @Success 200 {object} vo.ResponseVO{data=vo.PageVO} "成功"
+// @Failure 403 {object} vo.ResponseVO "失败"
+// @Router /admin/verifications [get]
+func (ac *AdminController) ListVerifications(c *gin.Context) {
+	page, size, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	verifications, total, err := ac.verificationService.ListVerifications(c.Query("status"), page, size)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(vo.PageVO{List: verifications, Total: total}))
+}
+
+// @Tags 管理模块
+// @Summary 查看认证证件
+// @Description 读取志愿者上传的证件照片，证件存放在私有桶中，只能通过该接口查看
+// @Produce image/jpeg,image/png
+// @Security ApiKeyAuth
+// @Param accountId path int true "志愿者ID"
+// @Param side path string true "证件面" Enums(front, back)
+// @Success 200 {file} file "证件照片"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /admin/verifications/{accountId}/documents/{side} [get]
+func (ac *AdminController) GetVerificationDocument(c *gin.Context) {
+	accountID, err := strconv.ParseUint(c.Param("accountId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.INVALID_ACCOUNT_ID))
+		return
+	}
+	verification, err := ac.verificationService.GetVerification(uint(accountID))
+	if err != nil {
+		ac.verificationError(c, err)
+		return
+	}
+
+	var objectName string
+	switch c.Param("side") {
+	case "front":
+		objectName = verification.IDFrontObject
+	case "back":
+		objectName = verification.IDBackObject
+	default:
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	client := factories.OssClientFactory.GetOssClient(factories.MINIO)
+	data, err := client.Download(factories.VERIFICATION_BUCKET, objectName)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, http.DetectContentType(data), data)
+}
+
+// @Tags 管理模块
+// @Summary 审核志愿者认证
+// @Description 通过或驳回待审核的认证，暂停已认证志愿者的接单资格，或恢复被暂停的认证
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param accountId path int true "志愿者ID"
+// @Param request body dto.VerificationReviewRequest true "审核结果"
+// @Success 200 {object} vo.ResponseVO{data=models.VolunteerVerification} "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /admin/verifications/{accountId} [put]
+func (ac *AdminController) ReviewVerification(c *gin.Context) {
+	accountID, err := strconv.ParseUint(c.Param("accountId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.INVALID_ACCOUNT_ID))
+		return
+	}
+	var req dto.VerificationReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	verification, err := ac.verificationService.Review(uint(accountID), utils.GetAccountIdInContext(c), req.Status, req.Remark)
+	if err != nil {
+		ac.verificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(verification))
+}
+
+func (ac *AdminController) verificationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, server_error.VerificationNotExistError),
+		errors.Is(err, server_error.VerificationInvalidTransitionError):
+		c.JSON(http.StatusBadRequest, vo.Fail(err.Error()))
+	default:
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+	}
+}
+
+func (ac *AdminController) accountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.ACCOUNT_NOT_EXIST))
+	case errors.Is(err, services.ErrCannotSuspendAdmin):
+		c.JSON(http.StatusForbidden, vo.Fail(constants.NO_PERMISSION))
+	default:
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+	}
+}
+
+// 解析分页参数，不合法时直接写入响应
+func parsePage(c *gin.Context) (int, int, bool) {
+	page := utils.CoverStr2Int(c.Query("page"), 1)
+	size := utils.CoverStr2Int(c.Query("size"), constants.DEFAULT_PAGE_SIZE)
+	if page < 1 || size < 1 || size > 100 {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return 0, 0, false
+	}
+	return page, size, true
+}
@@ -3,92 +3,154 @@ package controllers
 import (
 	"context"
 	"elderly-care-backend/common/constants"
+	"elderly-care-backend/dto"
 	. "elderly-care-backend/global"
 	"elderly-care-backend/models"
+	"elderly-care-backend/services"
 	"elderly-care-backend/utils"
 	"elderly-care-backend/vo"
 	"encoding/json"
-	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 	"log"
 	"net/http"
-	"strconv"
-	"sync"
 	"time"
 )
 
-// WebSocket 升级器
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // 在生产环境中应该验证来源
-	},
+// ChatManager 聊天：客户端通过实时网关发送 chat 帧，消息写入kafka异步落库后再投递给收发双方（群聊为全体成员）；
+// 接收方用 chat_receipt 帧确认送达和已读，离线期间的消息在重新连接后补发；
+// SOS等事件也通过它推送给指定用户
+type ChatManager struct {
+	gateway      *services.RealtimeGateway
+	chatService  *services.ChatService
+	groupService *services.ChatGroupService
+	voiceService *services.ChatVoiceService
 }
 
-// 客户端连接信息
-type Client struct {
-	Conn      *websocket.Conn
-	AccountID uint
+// 创建聊天管理器并注册到实时网关
+func NewConnectionManager(gateway *services.RealtimeGateway, chatService *services.ChatService,
+	groupService *services.ChatGroupService, voiceService *services.ChatVoiceService) *ChatManager {
+	manager := &ChatManager{gateway: gateway, chatService: chatService, groupService: groupService, voiceService: voiceService}
+	gateway.Handle(constants.WS_CHANNEL_CHAT, manager.handleChatFrame)
+	gateway.Handle(constants.WS_FRAME_CHAT_RECEIPT, manager.handleReceiptFrame)
+	gateway.Handle(constants.WS_FRAME_CHAT_SYNC, manager.handleSyncFrame)
+	gateway.Handle(constants.WS_FRAME_CHAT_RECALL, manager.handleRecallFrame)
+	gateway.Handle(constants.WS_FRAME_CHAT_EDIT, manager.handleEditFrame)
+	gateway.Handle(constants.WS_FRAME_CHAT_DELETE, manager.handleDeleteFrame)
+	gateway.OnConnect(manager.pushUndelivered)
+	return manager
 }
 
-// 连接管理器
-type ChatManager struct {
-	clients    map[uint]*Client
-	broadcast  chan models.Message
-	register   chan *Client
-	unregister chan *Client
-	mutex      sync.RWMutex //保证并发安全
+// 启动kafka消费
+func (manager *ChatManager) Start() {
+	manager.HandleMessage()
 }
 
-// 创建连接管理器
-func NewConnectionManager() *ChatManager {
-	return &ChatManager{
-		clients:    make(map[uint]*Client),
-		broadcast:  make(chan models.Message),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+// 处理客户端发来的聊天消息，校验不通过时只给这条消息回复错误帧，连接保持不变
+func (manager *ChatManager) handleChatFrame(accountID uint, data json.RawMessage) (interface{}, error) {
+	var req dto.MessageDTO
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, services.ErrFrameInvalid
 	}
+	message, err := manager.chatService.Validate(accountID, &req)
+	if err != nil {
+		return nil, err
+	}
+	// 语音时长以上传时服务端计算的为准
+	if message.Type == models.Voice {
+		if message.Duration, err = manager.voiceService.Duration(accountID, message.Content); err != nil {
+			return nil, err
+		}
+	}
+	// 收发双方（群聊为全体成员）的所有设备都会收到，发送方以此确认消息ID
+	recipients, err := manager.recipients(accountID, message)
+	if err != nil {
+		return nil, err
+	}
+
+	ID, err := RedisClient.Incr(context.Background(), constants.CHAT_ID_COUNT).Result()
+	if err != nil {
+		return nil, err
+	}
+	message.Time = time.Now()
+	message.ID = uint(ID)
+	message.Status = models.Sent
+	value, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	// 先写入kafka，写入失败时给发送方回复错误帧，接收方不会收到一条不会落库的消息
+	if err = KafkaOperators[constants.MESSAGE_TOPIC].Writer.WriteMessages(context.Background(), kafka.Message{
+		Value: value,
+	}); err != nil {
+		Logger.Error("Kafka 写入消息失败", zap.Uint("message_id", message.ID), zap.Error(err))
+		return nil, err
+	}
+	for _, recipient := range recipients {
+		manager.gateway.Publish(services.UserTopic(recipient), constants.WS_EVENT_CHAT_MESSAGE, message)
+	}
+	return nil, nil
 }
 
-// 启动连接管理器
-func (manager *ChatManager) Start() {
-	writer := KafkaOperators[constants.MESSAGE_TOPIC].Writer
-	go manager.HandleMessage()
-	for {
-		select {
-		case client := <-manager.register:
-			manager.mutex.Lock()
-			manager.clients[client.AccountID] = client
-			manager.mutex.Unlock()
-			log.Printf("用户 %d 已连接", client.AccountID)
+// 处理接收方的送达/已读回执，回执发给消息发送方，同时同步给确认方的其他设备
+func (manager *ChatManager) handleReceiptFrame(accountID uint, data json.RawMessage) (interface{}, error) {
+	var receipt dto.ChatReceiptDTO
+	if err := json.Unmarshal(data, &receipt); err != nil || (receipt.ContactID == 0) == (receipt.GroupID == 0) {
+		return nil, services.ErrFrameInvalid
+	}
+	var result *vo.ChatReceiptVO
+	var err error
+	if receipt.GroupID != 0 {
+		result, err = manager.chatService.AcknowledgeGroup(accountID, receipt.GroupID, receipt.MessageID, receipt.Status)
+	} else {
+		result, err = manager.chatService.Acknowledge(accountID, receipt.ContactID, receipt.MessageID, receipt.Status)
+	}
+	if err != nil {
+		return nil, err
+	}
+	manager.publishReceipt(result)
+	return nil, nil
+}
 
-		case client := <-manager.unregister:
-			manager.mutex.Lock()
-			if _, exists := manager.clients[client.AccountID]; exists {
-				delete(manager.clients, client.AccountID)
-				client.Conn.Close()
-				log.Printf("用户 %d 已断开连接", client.AccountID)
-			}
-			manager.mutex.Unlock()
+func (manager *ChatManager) publishReceipt(receipt *vo.ChatReceiptVO) {
+	if receipt.MessageID == 0 {
+		return
+	}
+	// 群聊回执只同步给确认方的其他设备
+	if receipt.From != 0 {
+		manager.gateway.Publish(services.UserTopic(receipt.From), constants.WS_EVENT_CHAT_RECEIPT, receipt)
+	}
+	manager.gateway.Publish(services.UserTopic(receipt.To), constants.WS_EVENT_CHAT_RECEIPT, receipt)
+}
 
-		case message := <-manager.broadcast:
-			// 给message两端的用户都发送，因为message经过了封装
-			manager.SendMessage(message.From, message)
-			manager.SendMessage(message.To, message)
-			data, err := json.Marshal(message)
-			if err != nil {
-				log.Printf("JSON 序列化失败: %v", err)
-				continue
-			}
-			//kafka写入消息
-			_ = writer.WriteMessages(context.Background(), kafka.Message{
-				Value: data,
-			})
-		}
+// 设备重连后拉取本地最后一条消息之后的消息，回复给发起同步的连接
+func (manager *ChatManager) handleSyncFrame(accountID uint, data json.RawMessage) (interface{}, error) {
+	var req struct {
+		LastMessageID uint `json:"last_message_id"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, services.ErrFrameInvalid
+	}
+	return manager.chatService.Sync(accountID, req.LastMessageID)
+}
+
+// 连接建立后补发还没有送达的消息，客户端收到后发送送达回执
+func (manager *ChatManager) pushUndelivered(accountID uint) ([]vo.WsEventVO, error) {
+	msgs, err := manager.chatService.Undelivered(accountID)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]vo.WsEventVO, 0, len(msgs))
+	for _, msg := range msgs {
+		events = append(events, vo.WsEventVO{
+			Type:  constants.WS_CHANNEL_CHAT,
+			Event: constants.WS_EVENT_CHAT_MESSAGE,
+			Topic: services.UserTopic(accountID),
+			Data:  msg,
+		})
 	}
+	return events, nil
 }
 
 // 异步处理kafka消息，包括更新联系列表和存储
@@ -106,19 +168,8 @@ func (manager *ChatManager) HandleMessage() {
 			log.Printf("JSON 反序列化失败: %v", err)
 			continue
 		}
-		Db.Create(&msg)
-		err = Db.Transaction(func(tx *gorm.DB) error {
-			if err1 := manager.handleContactList(msg.From, msg.To, msg.Time, tx); err1 != nil {
-				return err1
-			}
-			if err2 := manager.handleContactList(msg.To, msg.From, msg.Time, tx); err2 != nil {
-				return err2
-			}
-			return nil
-		})
-
-		if err != nil {
-			Logger.Error("Update ContactList error", zap.Error(err))
+		if err = manager.chatService.SaveMessage(&msg); err != nil {
+			Logger.Error("Save message error", zap.Uint("message_id", msg.ID), zap.Error(err))
 		}
 
 		err = reader.CommitMessages(context.Background(), kafkaMsg)
@@ -128,91 +179,37 @@ func (manager *ChatManager) HandleMessage() {
 	}
 }
 
-func (manager *ChatManager) handleContactList(accountID, contactID uint, lastChatTime time.Time, db *gorm.DB) error {
-	if err := db.Create(&models.ContactList{
-		AccountID:    accountID,
-		ContactID:    contactID,
-		LastChatTime: lastChatTime,
-	}).Error; err != nil {
-		if errors.Is(err, gorm.ErrDuplicatedKey) {
-			if err = db.Model(&models.ContactList{}).Where("account_id = ? and contact_id = ?", accountID, contactID).Update("last_chat_time", lastChatTime).Error; err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
-	}
-	return nil
+// PushEvent 向指定用户推送事件，用户不在线时放入离线队列，上线后补发
+func (manager *ChatManager) PushEvent(accountID uint, eventType string, data interface{}) {
+	manager.gateway.PushEvent(accountID, eventType, data)
 }
 
-func (manager *ChatManager) SendMessage(toID uint, message models.Message) {
-	manager.mutex.RLock()
-	// 发送给指定用户
-	if targetClient, exists := manager.clients[toID]; exists {
-		err := targetClient.Conn.WriteJSON(message)
-		if err != nil {
-			log.Printf("发送消息失败: %v", err)
-			// 删除该用户，先释放读锁再加写锁
-			manager.mutex.RUnlock()
-			manager.mutex.Lock()
-			delete(manager.clients, toID)
-			targetClient.Conn.Close()
-			log.Printf("用户 %s 已断开连接", toID)
-			manager.mutex.Unlock()
-		} else {
-			manager.mutex.RUnlock()
-		}
-	} else {
-		manager.mutex.RUnlock()
-	}
-}
-
-// 处理 WebSocket 连接
-func (manager *ChatManager) HandleWebSocket(c *gin.Context) {
-
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		log.Printf("WebSocket 升级失败: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "WebSocket 升级失败"})
-		return
-	}
-
-	client := &Client{
-		Conn:      conn,
-		AccountID: utils.GetAccountIdInContext(c),
-	}
-
-	manager.register <- client
-
-	defer func() {
-		manager.unregister <- client
-	}()
-
-	// 读取消息
-	for {
-		var message models.Message
-		err := conn.ReadJSON(&message)
-		if err != nil {
-			break
-		}
-		ID, err := RedisClient.Incr(context.Background(), constants.CHAT_ID_COUNT).Result()
-		if err != nil {
-			log.Printf("获取消息ID失败: %v", err)
-			break
+// NotifyVolunteers 向匹配到的志愿者推送SOS告警
+func (manager *ChatManager) NotifyVolunteers(record *models.SOSRecord, volunteers []map[string]interface{}) {
+	for _, volunteer := range volunteers {
+		volunteerID, ok := services.VolunteerID(volunteer)
+		if !ok {
+			continue
 		}
-
-		// 设置发送者
-		message.From = utils.GetAccountIdInContext(c)
-		message.Time = time.Now()
-		message.ID = uint(ID)
-		// 广播消息
-		manager.broadcast <- message
+		distance, _ := volunteer["distance"].(float64)
+		manager.PushEvent(volunteerID, constants.WS_EVENT_SOS_ALERT, vo.SOSAlertVO{
+			SOSID:       record.ID,
+			TaskID:      record.TaskID,
+			UserID:      record.UserID,
+			Latitude:    record.Latitude,
+			Longitude:   record.Longitude,
+			Address:     record.Address,
+			Description: record.Description,
+			Severity:    record.Severity,
+			Distance:    distance,
+			TimeoutAt:   record.TimeoutAt,
+		})
 	}
 }
 
 // @Tags 聊天模块
 // @Summary 聊天记录
-// @Description 聊天记录
+// @Description 聊天记录，不包含自己删除的消息；撤回的消息内容为空，RecalledAt 为撤回时间，编辑过的消息 EditedAt 为最后编辑时间
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
@@ -223,29 +220,16 @@ func (manager *ChatManager) HandleWebSocket(c *gin.Context) {
 // @Failure 500 {object} vo.ResponseVO "失败"
 // @Router /chat/record [get]
 func (manager *ChatManager) GetChatRecord(c *gin.Context) {
-	accountIDStr := c.Query("accountID")
-	messageIDStr := c.Query("messageID")
-	size := c.Query("size")
-	var accountID uint
-	if accountIDStr != "" {
-		t, _ := strconv.Atoi(accountIDStr)
-		accountID = uint(t)
-	}
-	query := Db
-	if messageIDStr != "" {
-		t, _ := strconv.Atoi(messageIDStr)
-		query = Db.Where("id < ?", t)
-	}
-	if size != "" {
-		t, _ := strconv.Atoi(size)
-		query = query.Limit(t)
-	} else {
-		query = query.Limit(constants.DEFAULT_PAGE_SIZE)
+	accountID := max(utils.CoverStr2Int(c.Query("accountID"), 0), 0)
+	messageID := max(utils.CoverStr2Int(c.Query("messageID"), 0), 0)
+	size := utils.CoverStr2Int(c.Query("size"), constants.DEFAULT_PAGE_SIZE)
+	if size <= 0 {
+		size = constants.DEFAULT_PAGE_SIZE
 	}
-	msgs := make([]models.Message, 0)
-	if query.Where(models.Message{From: utils.GetAccountIdInContext(c), To: accountID}).
-		Or(models.Message{From: accountID, To: utils.GetAccountIdInContext(c)}).Order("time desc").Find(&msgs).Error != nil {
+	msgs, err := manager.chatService.Record(utils.GetAccountIdInContext(c), uint(accountID), uint(messageID), size)
+	if err != nil {
 		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
 	}
 	c.JSON(http.StatusOK, vo.Success(msgs))
 }
@@ -266,8 +250,12 @@ func (manager *ChatManager) GetRecentlyChatList(c *gin.Context) {
 	timeStr := c.Query("lastChatTime")
 	contactList := make([]vo.ContactListVo, 0)
 	query := Db.Model(&models.ContactList{}).
-		Select("contact_list.contact_id,contact_list.last_chat_time, account.nickname, account.avatar").
-		Joins(" join account  on account.id = contact_list.contact_id").Where("account_id = ?", utils.GetAccountIdInContext(c)).Order("last_chat_time desc").Limit(size)
+		Select("contact_list.contact_id,contact_list.last_chat_time, account.nickname, account.avatar, contact_list.unread_count, "+
+			"contact_list.last_message_id, message.from AS last_message_from, message.type AS last_message_type, message.content AS last_message_preview, "+
+			"message.recalled_at IS NOT NULL AS last_message_recalled").
+		Joins(" join account  on account.id = contact_list.contact_id").
+		Joins("left join message on message.id = contact_list.last_message_id").
+		Where("account_id = ?", utils.GetAccountIdInContext(c)).Order("last_chat_time desc").Limit(size)
 	if timeStr != "" {
 		lastChatTime, err := time.Parse(time.RFC3339, timeStr)
 		if err != nil {
@@ -280,6 +268,37 @@ func (manager *ChatManager) GetRecentlyChatList(c *gin.Context) {
 		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
 		return
 	}
+	for i := range contactList {
+		contactList[i].LastMessagePreview = services.MessagePreview(contactList[i].LastMessageType, contactList[i].LastMessagePreview,
+			contactList[i].LastMessageRecalled)
+	}
 	c.JSON(http.StatusOK, vo.Success(contactList))
 
 }
+
+// @Tags 聊天模块
+// @Summary 标记会话已读
+// @Description 把联系人发来的消息标记为已读并清零未读数，已读回执会推送给对方和自己的其他设备
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body dto.ChatReadDTO true "联系人ID，message_id 为空时整个会话已读"
+// @Success 200 {object} vo.ResponseVO{data=vo.ChatReceiptVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "参数错误"
+// @Failure 502 {object} vo.ResponseVO "失败"
+// @Router /chat/read [put]
+func (manager *ChatManager) MarkRead(c *gin.Context) {
+	var req dto.ChatReadDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+	receipt, err := manager.chatService.Acknowledge(utils.GetAccountIdInContext(c), req.ContactID, req.MessageID, models.Read)
+	if err != nil {
+		Logger.Error("mark chat read error", zap.Error(err))
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	manager.publishReceipt(receipt)
+	c.JSON(http.StatusOK, vo.Success(receipt))
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/dto"
+	. "elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"elderly-care-backend/services"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// @Tags 聊天模块
+// @Summary 创建群聊
+// @Description 创建群聊，创建人为群主，member_ids 中的用户会收到邀请，接受后才能收发消息
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param data body dto.CreateChatGroupDTO true "群名称和邀请的成员"
+// @Success 200 {object} vo.ResponseVO{data=models.ChatGroup} "成功"
+// @Failure 400 {object} vo.ResponseVO "参数错误"
+// @Failure 502 {object} vo.ResponseVO "失败"
+// @Router /chat/groups [post]
+func (manager *ChatManager) CreateGroup(c *gin.Context) {
+	var req dto.CreateChatGroupDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+	group, invited, err := manager.groupService.CreateGroup(utils.GetAccountIdInContext(c), req.Name, req.MemberIDs)
+	if err != nil {
+		chatGroupError(c, err)
+		return
+	}
+	manager.notifyInvited(group.ID, group.Name, invited)
+	c.JSON(http.StatusOK, vo.Success(group))
+}
+
+// @Tags 聊天模块
+// @Summary 我的群聊
+// @Description 已加入和被邀请的群聊，包含未读数和最后一条消息预览
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} vo.ResponseVO{data=[]vo.ChatGroupVO} "成功"
+// @Failure 502 {object} vo.ResponseVO "失败"
+// @Router /chat/groups [get]
+func (manager *ChatManager) ListGroups(c *gin.Context) {
+	groups, err := manager.groupService.ListGroups(utils.GetAccountIdInContext(c))
+	if err != nil {
+		chatGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(groups))
+}
+
+// @Tags 聊天模块
+// @Summary 群聊详情
+// @Description 群信息和成员列表，成员和被邀请人可以查看
+// @Produce json
+// @Security ApiKeyAuth
+// @Param groupId path int true "群ID"
+// @Success 200 {object} vo.ResponseVO{data=vo.ChatGroupDetailVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "群不存在"
+// @Failure 403 {object} vo.ResponseVO "不是群成员"
+// @Router /chat/groups/{groupId} [get]
+func (manager *ChatManager) GetGroup(c *gin.Context) {
+	groupID, ok := groupIDParam(c)
+	if !ok {
+		return
+	}
+	detail, err := manager.groupService.GetGroup(utils.GetAccountIdInContext(c), groupID)
+	if err != nil {
+		chatGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(detail))
+}
+
+// @Tags 聊天模块
+// @Summary 邀请入群
+// @Description 群主邀请成员，已在群里或已被邀请的用户忽略，返回实际被邀请的用户ID
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param groupId path int true "群ID"
+// @Param data body dto.InviteChatGroupDTO true "被邀请的用户ID"
+// @Success 200 {object} vo.ResponseVO{data=[]uint} "成功"
+// @Failure 400 {object} vo.ResponseVO "参数错误或群已满"
+// @Failure 403 {object} vo.ResponseVO "不是群主"
+// @Router /chat/groups/{groupId}/invite [post]
+func (manager *ChatManager) InviteGroupMembers(c *gin.Context) {
+	groupID, ok := groupIDParam(c)
+	if !ok {
+		return
+	}
+	var req dto.InviteChatGroupDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+	invited, err := manager.groupService.Invite(utils.GetAccountIdInContext(c), groupID, req.AccountIDs)
+	if err != nil {
+		chatGroupError(c, err)
+		return
+	}
+	group := &models.ChatGroup{}
+	if err = Db.Select("id", "name").Take(group, groupID).Error; err == nil {
+		manager.notifyInvited(group.ID, group.Name, invited)
+	}
+	c.JSON(http.StatusOK, vo.Success(invited))
+}
+
+// @Tags 聊天模块
+// @Summary 加入群聊
+// @Description 接受邀请加入群聊，从加入时开始计算未读
+// @Produce json
+// @Security ApiKeyAuth
+// @Param groupId path int true "群ID"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 403 {object} vo.ResponseVO "没有被邀请"
+// @Router /chat/groups/{groupId}/join [post]
+func (manager *ChatManager) JoinGroup(c *gin.Context) {
+	groupID, ok := groupIDParam(c)
+	if !ok {
+		return
+	}
+	if err := manager.groupService.Join(utils.GetAccountIdInContext(c), groupID); err != nil {
+		chatGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 聊天模块
+// @Summary 退出群聊
+// @Description 退出群聊或拒绝邀请，群主退出时转让给最早加入的成员，没有其他成员时解散
+// @Produce json
+// @Security ApiKeyAuth
+// @Param groupId path int true "群ID"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 403 {object} vo.ResponseVO "不是群成员"
+// @Router /chat/groups/{groupId}/leave [post]
+func (manager *ChatManager) LeaveGroup(c *gin.Context) {
+	groupID, ok := groupIDParam(c)
+	if !ok {
+		return
+	}
+	if err := manager.groupService.Leave(utils.GetAccountIdInContext(c), groupID); err != nil {
+		chatGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 聊天模块
+// @Summary 群聊记录
+// @Description 群聊记录，按时间倒序分页
+// @Produce json
+// @Security ApiKeyAuth
+// @Param groupId path int true "群ID"
+// @Param messageID query uint false "消息ID(用于上滑加载，返回该消息之前的记录)"
+// @Param size query int false "返回数量"
+// @Success 200 {object} vo.ResponseVO{data=[]models.Message} "成功"
+// @Failure 403 {object} vo.ResponseVO "不是群成员"
+// @Router /chat/groups/{groupId}/record [get]
+func (manager *ChatManager) GetGroupChatRecord(c *gin.Context) {
+	groupID, ok := groupIDParam(c)
+	if !ok {
+		return
+	}
+	messageID := max(utils.CoverStr2Int(c.Query("messageID"), 0), 0)
+	size := utils.CoverStr2Int(c.Query("size"), constants.DEFAULT_PAGE_SIZE)
+	if size <= 0 {
+		size = constants.DEFAULT_PAGE_SIZE
+	}
+	msgs, err := manager.groupService.Record(utils.GetAccountIdInContext(c), groupID, uint(messageID), size)
+	if err != nil {
+		chatGroupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(msgs))
+}
+
+// @Tags 聊天模块
+// @Summary 标记群聊已读
+// @Description 群内 ID 不超过 messageID 的消息标记为已读，messageID 为空时全部已读，回执同步给自己的其他设备
+// @Produce json
+// @Security ApiKeyAuth
+// @Param groupId path int true "群ID"
+// @Param messageID query uint false "消息ID"
+// @Success 200 {object} vo.ResponseVO{data=vo.ChatReceiptVO} "成功"
+// @Failure 403 {object} vo.ResponseVO "不是群成员"
+// @Router /chat/groups/{groupId}/read [put]
+func (manager *ChatManager) MarkGroupRead(c *gin.Context) {
+	groupID, ok := groupIDParam(c)
+	if !ok {
+		return
+	}
+	messageID := max(utils.CoverStr2Int(c.Query("messageID"), 0), 0)
+	receipt, err := manager.chatService.AcknowledgeGroup(utils.GetAccountIdInContext(c), groupID, uint(messageID), models.Read)
+	if err != nil {
+		chatGroupError(c, err)
+		return
+	}
+	manager.publishReceipt(receipt)
+	c.JSON(http.StatusOK, vo.Success(receipt))
+}
+
+// 通知被邀请人，不在线时上线后补发
+func (manager *ChatManager) notifyInvited(groupID uint, name string, invited []uint) {
+	for _, accountID := range invited {
+		manager.PushEvent(accountID, constants.WS_EVENT_CHAT_GROUP_INVITED, gin.H{
+			"group_id": groupID,
+			"name":     name,
+		})
+	}
+}
+
+func groupIDParam(c *gin.Context) (uint, bool) {
+	groupID, err := strconv.ParseUint(c.Param("groupId"), 10, 32)
+	if err != nil || groupID == 0 {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return 0, false
+	}
+	return uint(groupID), true
+}
+
+func chatGroupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrChatGroupNotMember),
+		errors.Is(err, services.ErrChatGroupNotOwner),
+		errors.Is(err, services.ErrChatGroupNotInvited):
+		c.JSON(http.StatusForbidden, vo.Fail(err.Error()))
+	case errors.Is(err, services.ErrChatGroupNotExist),
+		errors.Is(err, services.ErrChatGroupFull):
+		c.JSON(http.StatusBadRequest, vo.Fail(err.Error()))
+	default:
+		Logger.Error("chat group error", zap.Error(err))
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+	}
+}
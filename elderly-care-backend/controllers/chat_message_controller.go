@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/dto"
+	. "elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"elderly-care-backend/services"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 消息的接收方：单聊为收发双方，群聊为全体已加入的成员，发送方必须仍在群里
+func (manager *ChatManager) recipients(accountID uint, message *models.Message) ([]uint, error) {
+	if message.GroupID != 0 {
+		return manager.groupService.JoinedMemberIDs(accountID, message.GroupID)
+	}
+	if message.To == message.From {
+		return []uint{message.From}, nil
+	}
+	return []uint{message.From, message.To}, nil
+}
+
+// 撤回和编辑后的消息推送给所有接收方
+func (manager *ChatManager) publishChange(eventType string, message *models.Message) error {
+	recipients, err := manager.recipients(message.From, message)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		manager.gateway.Publish(services.UserTopic(recipient), eventType, message)
+	}
+	return nil
+}
+
+func (manager *ChatManager) recall(accountID, messageID uint) (*models.Message, error) {
+	message, err := manager.chatService.Recall(accountID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	return message, manager.publishChange(constants.WS_EVENT_CHAT_RECALLED, message)
+}
+
+func (manager *ChatManager) edit(accountID, messageID uint, content string) (*models.Message, error) {
+	message, err := manager.chatService.Edit(accountID, messageID, content)
+	if err != nil {
+		return nil, err
+	}
+	return message, manager.publishChange(constants.WS_EVENT_CHAT_EDITED, message)
+}
+
+// 删除只对自己生效，同步给自己的其他设备
+func (manager *ChatManager) delete(accountID, messageID uint) error {
+	message, err := manager.chatService.Delete(accountID, messageID)
+	if err != nil {
+		return err
+	}
+	manager.gateway.Publish(services.UserTopic(accountID), constants.WS_EVENT_CHAT_DELETED, gin.H{
+		"message_id": message.ID,
+		"group_id":   message.GroupID,
+	})
+	return nil
+}
+
+func decodeMessageOp(data json.RawMessage) (*dto.ChatMessageOpDTO, error) {
+	var req dto.ChatMessageOpDTO
+	if err := json.Unmarshal(data, &req); err != nil || req.MessageID == 0 {
+		return nil, services.ErrFrameInvalid
+	}
+	return &req, nil
+}
+
+func (manager *ChatManager) handleRecallFrame(accountID uint, data json.RawMessage) (interface{}, error) {
+	req, err := decodeMessageOp(data)
+	if err != nil {
+		return nil, err
+	}
+	_, err = manager.recall(accountID, req.MessageID)
+	return nil, err
+}
+
+func (manager *ChatManager) handleEditFrame(accountID uint, data json.RawMessage) (interface{}, error) {
+	req, err := decodeMessageOp(data)
+	if err != nil {
+		return nil, err
+	}
+	_, err = manager.edit(accountID, req.MessageID, req.Content)
+	return nil, err
+}
+
+func (manager *ChatManager) handleDeleteFrame(accountID uint, data json.RawMessage) (interface{}, error) {
+	req, err := decodeMessageOp(data)
+	if err != nil {
+		return nil, err
+	}
+	return nil, manager.delete(accountID, req.MessageID)
+}
+
+// @Tags 聊天模块
+// @Summary 撤回消息
+// @Description 发送方在时限内(默认2分钟)撤回消息，内容清空，所有接收方收到 chat_recalled 事件
+// @Produce json
+// @Security ApiKeyAuth
+// @Param messageId path int true "消息ID"
+// @Success 200 {object} vo.ResponseVO{data=models.Message} "成功"
+// @Failure 400 {object} vo.ResponseVO "消息不存在、已撤回或超过撤回时限"
+// @Failure 403 {object} vo.ResponseVO "不是发送方"
+// @Router /chat/messages/{messageId}/recall [post]
+func (manager *ChatManager) RecallMessage(c *gin.Context) {
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+	message, err := manager.recall(utils.GetAccountIdInContext(c), messageID)
+	if err != nil {
+		chatMessageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(message))
+}
+
+// @Tags 聊天模块
+// @Summary 编辑消息
+// @Description 发送方编辑自己的文本消息，记录编辑时间，所有接收方收到 chat_edited 事件
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param messageId path int true "消息ID"
+// @Param data body dto.EditChatMessageDTO true "新的内容"
+// @Success 200 {object} vo.ResponseVO{data=models.Message} "成功"
+// @Failure 400 {object} vo.ResponseVO "参数错误、消息不存在、已撤回或不是文本消息"
+// @Failure 403 {object} vo.ResponseVO "不是发送方"
+// @Router /chat/messages/{messageId} [put]
+func (manager *ChatManager) EditMessage(c *gin.Context) {
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+	var req dto.EditChatMessageDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+	message, err := manager.edit(utils.GetAccountIdInContext(c), messageID, req.Content)
+	if err != nil {
+		chatMessageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(message))
+}
+
+// @Tags 聊天模块
+// @Summary 删除消息
+// @Description 在自己这一侧删除消息，对方和其他群成员不受影响，自己的其他设备收到 chat_deleted 事件
+// @Produce json
+// @Security ApiKeyAuth
+// @Param messageId path int true "消息ID"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "消息不存在"
+// @Router /chat/messages/{messageId} [delete]
+func (manager *ChatManager) DeleteMessage(c *gin.Context) {
+	messageID, ok := messageIDParam(c)
+	if !ok {
+		return
+	}
+	if err := manager.delete(utils.GetAccountIdInContext(c), messageID); err != nil {
+		chatMessageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+func messageIDParam(c *gin.Context) (uint, bool) {
+	messageID, err := strconv.ParseUint(c.Param("messageId"), 10, 32)
+	if err != nil || messageID == 0 {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return 0, false
+	}
+	return uint(messageID), true
+}
+
+func chatMessageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMessageNotSender),
+		errors.Is(err, services.ErrChatGroupNotMember):
+		c.JSON(http.StatusForbidden, vo.Fail(err.Error()))
+	case errors.Is(err, services.ErrMessageNotExist),
+		errors.Is(err, services.ErrMessageRecalled),
+		errors.Is(err, services.ErrMessageRecallExpired),
+		errors.Is(err, services.ErrMessageNotEditable),
+		errors.Is(err, services.ErrMessageContentInvalid),
+		errors.Is(err, services.ErrMessageTooLong):
+		c.JSON(http.StatusBadRequest, vo.Fail(err.Error()))
+	default:
+		Logger.Error("chat message error", zap.Error(err))
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+	}
+}
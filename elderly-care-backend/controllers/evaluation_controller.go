@@ -2,55 +2,144 @@ package controllers
 
 import (
 	"elderly-care-backend/common/constants"
+	"elderly-care-backend/dto"
 	. "elderly-care-backend/global"
 	"elderly-care-backend/models"
+	"elderly-care-backend/services"
+	"elderly-care-backend/utils"
 	"elderly-care-backend/vo"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-type EvaluationController struct{}
+type EvaluationController struct {
+	evaluationService *services.EvaluationService
+	accessService     *services.TaskAccessService
+}
+
+func NewEvaluationController() *EvaluationController {
+	return &EvaluationController{
+		evaluationService: services.NewEvaluationService(Db),
+		accessService:     services.NewTaskAccessService(Db),
+	}
+}
 
 // @Tags 评价模块
-// @Summary 评价账号
-// @Description 任务完成后对任务进行评价
+// @Summary 评价任务
+// @Description 任务完成后发布人和接单人互相评价，每人每个任务只能评价一次
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Param  accountID query uint true "评价的账号ID"
-// @Param  score query uint false "得分（1-5分）"
+// @Param request body dto.TaskEvaluationRequest true "评价内容"
 // @Success 200 {object} vo.ResponseVO "成功"
 // @Failure 500 {object} vo.ResponseVO "失败"
-// @Router /evaluation/account [put]
-func (ce *EvaluationController) EvaluationAccount(c *gin.Context) {
-	assignIDStr := c.Query("accountID")
-	scoreStr := c.Query("score")
-	if assignIDStr == "" || scoreStr == "" {
+// @Router /evaluation/task [post]
+func (ce *EvaluationController) EvaluateTask(c *gin.Context) {
+	var req dto.TaskEvaluationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
-	} else {
-		assignID, err := strconv.Atoi(assignIDStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
-			return
+		return
+	}
+
+	var task models.Task
+	if err := Db.Take(&task, req.TaskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.TASK_NOT_EXIST))
+		} else {
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
 		}
-		score, err := strconv.Atoi(scoreStr)
-		//判断得分是否正常
-		if score < 1 || score > 5 {
-			c.JSON(http.StatusBadRequest, vo.Fail(constants.EVALUATION_ERROR))
-			return
+		return
+	}
+	if task.Status != constants.TASK_STATUS_COMPLETED || task.AssigneeID == nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.TASK_NOT_COMPLETED))
+		return
+	}
+
+	// 只有任务双方可以评价，评价对象为另一方
+	raterID := utils.GetAccountIdInContext(c)
+	var rateeID uint
+	switch raterID {
+	case task.CreatorID:
+		rateeID = *task.AssigneeID
+	case *task.AssigneeID:
+		rateeID = task.CreatorID
+	default:
+		c.JSON(http.StatusForbidden, vo.Fail(constants.EVALUATION_NOT_ALLOWED))
+		return
+	}
+
+	err := Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.TaskEvaluation{
+			TaskID:  task.ID,
+			RaterID: raterID,
+			RateeID: rateeID,
+			Score:   req.Score,
+			Comment: req.Comment,
+			Tags:    strings.Join(req.Tags, ","),
+		}).Error; err != nil {
+			return err
 		}
-		//更新评价
-		if Db.Model(&models.AccountEvaluation{}).Where("account_id = ?", assignID).Updates(map[string]interface{}{
-			"score":        gorm.Expr("score + ?", score),
-			"assign_count": gorm.Expr("assign_count + 1")}).Error != nil {
+		return ce.evaluationService.RecomputeAccountEvaluation(tx, rateeID)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.EVALUATION_EXISTS))
+		} else {
 			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
-			return
 		}
-		c.JSON(http.StatusOK, vo.Success(nil))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 评价模块
+// @Summary 获取任务评价
+// @Description 获取任务双方的评价，限发布人、接单人、发布人已确认的监护人和管理员/运营人员查看
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param  taskID query uint true "任务ID"
+// @Success 200 {object} vo.ResponseVO{data=[]models.TaskEvaluation} "成功"
+// @Failure 400 {object} vo.ResponseVO "任务不存在"
+// @Failure 403 {object} vo.ResponseVO "无权查看"
+// @Failure 500 {object} vo.ResponseVO "失败"
+// @Router /evaluation/task [get]
+func (ce *EvaluationController) GetTaskEvaluations(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Query("taskID"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+	var task models.Task
+	if err = Db.Select("id", "creator_id", "assignee_id").Take(&task, uint(taskID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.TASK_NOT_EXIST))
+		} else {
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return
+	}
+	allowed, err := ce.accessService.CanView(utils.GetAccountIdInContext(c), utils.GetRoleInContext(c), task.CreatorID, task.AssigneeID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, vo.Fail(constants.NO_PERMISSION))
+		return
+	}
+
+	evaluations := make([]models.TaskEvaluation, 0)
+	if err = Db.Where("task_id = ?", task.ID).Order("id asc").Find(&evaluations).Error; err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
 	}
+	c.JSON(http.StatusOK, vo.Success(evaluations))
 }
 
 // @Tags 评价模块
@@ -3,7 +3,10 @@ package controllers
 import (
 	"elderly-care-backend/common/constants"
 	"elderly-care-backend/common/factories"
+	"elderly-care-backend/services"
+	"elderly-care-backend/utils"
 	"elderly-care-backend/vo"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
@@ -11,6 +14,11 @@ import (
 )
 
 type FileController struct {
+	voiceService *services.ChatVoiceService
+}
+
+func NewFileController(voiceService *services.ChatVoiceService) *FileController {
+	return &FileController{voiceService: voiceService}
 }
 
 // @Tags 文件模块
@@ -42,3 +50,41 @@ func (fc *FileController) UploadFile(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, vo.Success(url))
 }
+
+// @Tags 文件模块
+// @Summary 语音上传
+// @Description 上传聊天语音(mp3/wav/flac)，服务端校验格式和大小并计算时长，时长需在 1~60 秒之间；
+// @Description 发送语音消息时 type 为 3，content 填写返回的 url，时长以服务端计算的为准
+// @Accept mpfd
+// @Produce json
+// @Security ApiKeyAuth
+// @Param file formData file true "语音文件"
+// @Success 200 {object} vo.ResponseVO{data=vo.ChatVoiceVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "格式错误、文件过大或时长不符合要求"
+// @Failure 502 {object} vo.ResponseVO "失败"
+// @Router /file/voice [post]
+func (fc *FileController) UploadVoice(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.FILE_UPLOAD_ERROR))
+		return
+	}
+	voice, err := fc.voiceService.Upload(utils.GetAccountIdInContext(c), fileHeader)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrVoiceFormat),
+			errors.Is(err, services.ErrVoiceTooLarge),
+			errors.Is(err, services.ErrVoiceTooShort),
+			errors.Is(err, services.ErrVoiceTooLong):
+			c.JSON(http.StatusBadRequest, vo.Fail(err.Error()))
+		case errors.Is(err, services.ErrVoiceOpen):
+			c.JSON(http.StatusInternalServerError, vo.Fail(err.Error()))
+		case errors.Is(err, services.ErrVoiceUpload):
+			c.JSON(http.StatusBadGateway, vo.Fail(err.Error()))
+		default:
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(voice))
+}
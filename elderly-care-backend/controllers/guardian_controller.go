@@ -0,0 +1,388 @@
+package controllers
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/dto/account_dto"
+	. "elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"elderly-care-backend/services"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type GuardianController struct {
+	guardianService      *services.GuardianService
+	locationService      *services.RealtimeLocationService
+	passwordResetService *services.PasswordResetService
+}
+
+func NewGuardianController() *GuardianController {
+	return &GuardianController{
+		guardianService:      services.NewGuardianService(Db),
+		locationService:      services.NewRealtimeLocationService(Db),
+		passwordResetService: services.NewPasswordResetService(Db, RedisClient),
+	}
+}
+
+// @Tags 监护人模块
+// @Summary 添加监护人
+// @Description 老人通过手机号邀请已注册的家属成为监护人，需监护人确认后生效；被拒绝后可以重新邀请
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body account_dto.GuardianAddDTO true "监护人信息"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/guardians [post]
+func (gc *GuardianController) AddGuardian(c *gin.Context) {
+	dto := &account_dto.GuardianAddDTO{}
+	if err := c.ShouldBindJSON(dto); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	elderID := utils.GetAccountIdInContext(c)
+	guardianAccount := &models.Account{}
+	if err := Db.Where("phone = ?", dto.Phone).Take(guardianAccount).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.ACCOUNT_NOT_EXIST))
+		} else {
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return
+	}
+	if guardianAccount.ID == elderID {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.GUARDIAN_SELF))
+		return
+	}
+	if guardianAccount.Role != constants.ROLE_GUARDIAN {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.GUARDIAN_ROLE_REQUIRED))
+		return
+	}
+
+	guardian := &models.Guardian{
+		ElderID:        elderID,
+		GuardianID:     guardianAccount.ID,
+		Relationship:   dto.Relationship,
+		Priority:       dto.Priority,
+		Status:         constants.GUARDIAN_STATUS_PENDING,
+		ElderConsentAt: time.Now(),
+	}
+	err := Db.Create(guardian).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		err = gc.reinvite(guardian)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.GUARDIAN_EXIST))
+		} else {
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(guardian.ID))
+}
+
+// 已拒绝的邀请重新变为待确认，待确认和已确认的关系仍然返回 ErrDuplicatedKey
+func (gc *GuardianController) reinvite(guardian *models.Guardian) error {
+	result := Db.Model(&models.Guardian{}).
+		Where("elder_id = ? AND guardian_id = ? AND status = ?", guardian.ElderID, guardian.GuardianID, constants.GUARDIAN_STATUS_REJECTED).
+		Updates(map[string]interface{}{
+			"relationship":     guardian.Relationship,
+			"priority":         guardian.Priority,
+			"status":           constants.GUARDIAN_STATUS_PENDING,
+			"elder_consent_at": guardian.ElderConsentAt,
+			"verified_at":      nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return Db.Select("id").Where("elder_id = ? AND guardian_id = ?", guardian.ElderID, guardian.GuardianID).Take(guardian).Error
+}
+
+// @Tags 监护人模块
+// @Summary 我的监护人
+// @Description 老人查看自己的监护人列表
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} vo.ResponseVO{data=[]vo.GuardianVO} "成功"
+// @Failure 500 {object} vo.ResponseVO "失败"
+// @Router /account/guardians [get]
+func (gc *GuardianController) GetGuardians(c *gin.Context) {
+	guardians := make([]vo.GuardianVO, 0)
+	if err := Db.Model(&models.Guardian{}).
+		Select("guardian.*, account.nickname, account.avatar, account.phone").
+		Joins("join account on account.id = guardian.guardian_id").
+		Where("guardian.elder_id = ?", utils.GetAccountIdInContext(c)).
+		Order("guardian.priority asc").Find(&guardians).Error; err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(guardians))
+}
+
+// @Tags 监护人模块
+// @Summary 我监护的老人
+// @Description 监护人查看自己监护（含待确认）的老人列表
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} vo.ResponseVO{data=[]vo.GuardianVO} "成功"
+// @Failure 500 {object} vo.ResponseVO "失败"
+// @Router /account/wards [get]
+func (gc *GuardianController) GetWards(c *gin.Context) {
+	wards := make([]vo.GuardianVO, 0)
+	if err := Db.Model(&models.Guardian{}).
+		Select("guardian.*, account.nickname, account.avatar, account.phone").
+		Joins("join account on account.id = guardian.elder_id").
+		Where("guardian.guardian_id = ?", utils.GetAccountIdInContext(c)).
+		Order("guardian.created_at desc").Find(&wards).Error; err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(wards))
+}
+
+// @Tags 监护人模块
+// @Summary 修改监护人
+// @Description 老人修改监护人的关系和通知顺序
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "监护关系ID"
+// @Param request body account_dto.GuardianUpdateDTO true "监护人信息"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/guardians/{id} [put]
+func (gc *GuardianController) UpdateGuardian(c *gin.Context) {
+	dto := &account_dto.GuardianUpdateDTO{}
+	if err := c.ShouldBindJSON(dto); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+	guardian, ok := gc.getGuardian(c)
+	if !ok {
+		return
+	}
+	if guardian.ElderID != utils.GetAccountIdInContext(c) {
+		c.JSON(http.StatusForbidden, vo.Fail(constants.NO_PERMISSION))
+		return
+	}
+	if err := Db.Model(guardian).Updates(map[string]interface{}{
+		"relationship": dto.Relationship,
+		"priority":     dto.Priority,
+	}).Error; err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 监护人模块
+// @Summary 解除监护关系
+// @Description 老人或监护人任意一方都可以解除监护关系
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "监护关系ID"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/guardians/{id} [delete]
+func (gc *GuardianController) RemoveGuardian(c *gin.Context) {
+	guardian, ok := gc.getGuardian(c)
+	if !ok {
+		return
+	}
+	accountID := utils.GetAccountIdInContext(c)
+	if guardian.ElderID != accountID && guardian.GuardianID != accountID {
+		c.JSON(http.StatusForbidden, vo.Fail(constants.NO_PERMISSION))
+		return
+	}
+	if err := Db.Delete(guardian).Error; err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 监护人模块
+// @Summary 确认监护关系
+// @Description 被邀请的监护人确认关系，确认后可接收SOS告警并查看老人状态
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "监护关系ID"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/guardians/{id}/confirm [put]
+func (gc *GuardianController) ConfirmGuardian(c *gin.Context) {
+	now := time.Now()
+	gc.answerInvitation(c, map[string]interface{}{
+		"status":      constants.GUARDIAN_STATUS_VERIFIED,
+		"verified_at": &now,
+	})
+}
+
+// @Tags 监护人模块
+// @Summary 拒绝监护关系
+// @Description 被邀请的监护人拒绝关系
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "监护关系ID"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /account/guardians/{id}/reject [put]
+func (gc *GuardianController) RejectGuardian(c *gin.Context) {
+	gc.answerInvitation(c, map[string]interface{}{
+		"status":      constants.GUARDIAN_STATUS_REJECTED,
+		"verified_at": nil,
+	})
+}
+
+func (gc *GuardianController) answerInvitation(c *gin.Context, updates map[string]interface{}) {
+	guardian, ok := gc.getGuardian(c)
+	if !ok {
+		return
+	}
+	// 只有被邀请的监护人本人可以确认或拒绝
+	if guardian.GuardianID != utils.GetAccountIdInContext(c) {
+		c.JSON(http.StatusForbidden, vo.Fail(constants.NO_PERMISSION))
+		return
+	}
+	// 只能答复待确认的邀请，已确认或已拒绝的关系不能再改变
+	result := Db.Model(guardian).Where("status = ?", constants.GUARDIAN_STATUS_PENDING).Updates(updates)
+	if result.Error != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.GUARDIAN_NOT_PENDING))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 监护人模块
+// @Summary 查看老人当前SOS
+// @Description 监护人查看老人进行中的SOS求助
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param elderId path int true "老人账号ID"
+// @Success 200 {object} vo.ResponseVO{data=vo.SOSRecordVO} "成功"
+// @Failure 403 {object} vo.ResponseVO "无权限"
+// @Router /account/wards/{elderId}/sos [get]
+func (gc *GuardianController) GetWardSOS(c *gin.Context) {
+	elderID, ok := gc.checkWard(c)
+	if !ok {
+		return
+	}
+	sosVO, err := findCurrentSOS(elderID)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Success(nil))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(sosVO))
+}
+
+// @Tags 监护人模块
+// @Summary 查看老人最新位置
+// @Description 监护人查看老人最近一次上报的位置
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param elderId path int true "老人账号ID"
+// @Success 200 {object} vo.ResponseVO{data=models.UserLocation} "成功"
+// @Failure 403 {object} vo.ResponseVO "无权限"
+// @Router /account/wards/{elderId}/location [get]
+func (gc *GuardianController) GetWardLocation(c *gin.Context) {
+	elderID, ok := gc.checkWard(c)
+	if !ok {
+		return
+	}
+	location, err := gc.locationService.GetUserCurrentLocation(elderID)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Success(nil))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(location))
+}
+
+func (gc *GuardianController) getGuardian(c *gin.Context) (*models.Guardian, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return nil, false
+	}
+	guardian := &models.Guardian{}
+	if err = Db.Take(guardian, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.GUARDIAN_NOT_EXIST))
+		} else {
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return nil, false
+	}
+	return guardian, true
+}
+
+// @Tags 监护人模块
+// @Summary 协助老人重置密码
+// @Description 已确认的监护人为老人设置新密码，老人所有已登录的会话失效，并短信通知老人
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param elderId path int true "老人账号ID"
+// @Param request body account_dto.AssistedResetDTO true "新密码"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 403 {object} vo.ResponseVO "无权限"
+// @Router /account/wards/{elderId}/password [put]
+func (gc *GuardianController) ResetWardPassword(c *gin.Context) {
+	elderID, ok := gc.checkWard(c)
+	if !ok {
+		return
+	}
+	dto := &account_dto.AssistedResetDTO{}
+	if err := c.ShouldBindJSON(dto); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	if err := gc.passwordResetService.AssistedReset(c.Request.Context(),
+		utils.GetAccountIdInContext(c), elderID, dto.NewPassword); err != nil {
+		passwordResetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// 校验当前用户是路径中老人的已确认监护人
+func (gc *GuardianController) checkWard(c *gin.Context) (uint, bool) {
+	elderID, err := strconv.Atoi(c.Param("elderId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return 0, false
+	}
+	isGuardian, err := gc.guardianService.IsGuardian(utils.GetAccountIdInContext(c), uint(elderID))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return 0, false
+	}
+	if !isGuardian {
+		c.JSON(http.StatusForbidden, vo.Fail(constants.NO_PERMISSION))
+		return 0, false
+	}
+	return uint(elderID), true
+}
@@ -17,20 +17,23 @@ import (
 type LocationController struct {
 	amapService     *services.AMapService
 	locationService *services.RealtimeLocationService
-	wsService       *services.WebSocketService
+	wsService       *services.RealtimeGateway
 	addressService  *services.AddressService
+	privacyService  *services.LocationPrivacyService
 }
 
 func NewLocationController(
 	amapService *services.AMapService,
 	locationService *services.RealtimeLocationService,
-	wsService *services.WebSocketService,
+	wsService *services.RealtimeGateway,
+	privacyService *services.LocationPrivacyService,
 ) *LocationController {
 	return &LocationController{
 		amapService:     amapService,
 		locationService: locationService,
 		wsService:       wsService,
 		addressService:  services.NewAddressService(global.Db),
+		privacyService:  privacyService,
 	}
 }
 
@@ -70,9 +73,9 @@ func (lc *LocationController) UpdateLocation(c *gin.Context) { // 首字母大
 		return
 	}
 
-	// 广播位置更新给相关用户
+	// 只推送给监护人和进行中任务的接单志愿者
 	if lc.wsService != nil {
-		lc.wsService.BroadcastMessage("user_location_updated", map[string]interface{}{
+		lc.wsService.PublishLocation(userID, map[string]interface{}{
 			"user_id":   userID,
 			"latitude":  update.Latitude,
 			"longitude": update.Longitude,
@@ -94,7 +97,7 @@ func (lc *LocationController) UpdateLocation(c *gin.Context) { // 首字母大
 
 // @Tags 实时定位
 // @Summary 获取用户当前位置
-// @Description 获取指定用户的当前位置
+// @Description 获取指定用户的当前位置，只有本人、已确认的监护人和进行中任务的接单志愿者可以查看
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
@@ -109,6 +112,10 @@ func (lc *LocationController) GetUserLocation(c *gin.Context) { // 首字母大
 		return
 	}
 
+	if !lc.checkCanView(c, uint(userID)) {
+		return
+	}
+
 	location, err := lc.locationService.GetUserCurrentLocation(uint(userID))
 	if err != nil {
 		c.JSON(http.StatusOK, vo.Success(nil))
@@ -127,7 +134,7 @@ func (lc *LocationController) GetUserLocation(c *gin.Context) { // 首字母大
 // @Param lat query number true "纬度"
 // @Param lng query number true "经度"
 // @Param radius query number false "搜索半径" default(5000)
-// @Param role query string false "用户角色" Enums(volunteer, user)
+// @Param role query string false "用户角色，只能查询志愿者" Enums(volunteer)
 // @Success 200 {object} vo.ResponseVO{data=[]interface{}}
 // @Router /location/nearby [get]
 func (lc *LocationController) GetNearbyUsers(c *gin.Context) { // 首字母大写
@@ -149,7 +156,12 @@ func (lc *LocationController) GetNearbyUsers(c *gin.Context) { // 首字母大
 	}
 
 	if role == "" {
-		role = "volunteer"
+		role = constants.ROLE_VOLUNTEER
+	}
+	// 老人等其他角色的位置不对外公开
+	if role != constants.ROLE_VOLUNTEER {
+		c.JSON(http.StatusOK, vo.Fail(constants.NO_PERMISSION))
+		return
 	}
 
 	users, err := lc.locationService.GetNearbyUsers(lat, lng, radius, role)
@@ -300,6 +312,10 @@ func (lc *LocationController) NavigateToUser(c *gin.Context) {
 		return
 	}
 
+	if !lc.checkCanView(c, uint(targetUserID)) {
+		return
+	}
+
 	currentLocation, err := lc.addressService.GetUserCurrentLocation(uint(currentUserID))
 	if err != nil || currentLocation == nil {
 		c.JSON(http.StatusBadRequest, vo.Fail("请先更新您的位置信息"))
@@ -373,6 +389,9 @@ func (lc *LocationController) NavigateToLocation(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, vo.Fail("目标位置不存在"))
 		return
 	}
+	if !lc.checkCanView(c, targetLocation.UserID) {
+		return
+	}
 
 	from := lc.formatCoordinates(currentLocation.Longitude, currentLocation.Latitude)
 	to := lc.formatCoordinates(targetLocation.Longitude, targetLocation.Latitude)
@@ -392,6 +411,20 @@ func (lc *LocationController) NavigateToLocation(c *gin.Context) {
 	c.JSON(http.StatusOK, vo.Success(response))
 }
 
+// 校验当前用户能否查看 ownerID 的位置
+func (lc *LocationController) checkCanView(c *gin.Context, ownerID uint) bool {
+	canView, err := lc.privacyService.CanView(utils.GetAccountIdInContext(c), ownerID)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
+		return false
+	}
+	if !canView {
+		c.JSON(http.StatusOK, vo.Fail(constants.NO_PERMISSION))
+		return false
+	}
+	return true
+}
+
 // 辅助函数：格式化坐标
 func (lc *LocationController) formatCoordinates(lng, lat float64) string {
 	return strconv.FormatFloat(lng, 'f', 6, 64) + "," + strconv.FormatFloat(lat, 'f', 6, 64)
@@ -2,32 +2,50 @@ package controllers
 
 import (
 	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/server_error"
 	"elderly-care-backend/dto"
 	"elderly-care-backend/global"
 	"elderly-care-backend/models"
 	"elderly-care-backend/services"
 	"elderly-care-backend/utils"
 	"elderly-care-backend/vo"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type SOSController struct {
-	matchingService *services.TaskMatchingService
+	matchingService     *services.TaskMatchingService
+	escalationService   *services.SOSEscalationService
+	guardianService     *services.GuardianService
+	lifecycleService    *services.TaskLifecycleService
+	verificationService *services.VolunteerVerificationService
+	accessService       *services.TaskAccessService
+	chatManager         *ChatManager
+	publisher           services.TopicPublisher
 }
 
-func NewSOSController() *SOSController {
+func NewSOSController(escalationService *services.SOSEscalationService, chatManager *ChatManager, publisher services.TopicPublisher) *SOSController {
 	return &SOSController{
-		matchingService: &services.TaskMatchingService{},
+		matchingService:     &services.TaskMatchingService{},
+		escalationService:   escalationService,
+		guardianService:     services.NewGuardianService(global.Db),
+		lifecycleService:    services.NewTaskLifecycleService(global.Db),
+		verificationService: services.NewVolunteerVerificationService(global.Db),
+		accessService:       services.NewTaskAccessService(global.Db),
+		chatManager:         chatManager,
+		publisher:           publisher,
 	}
 }
 
 // @Tags SOS模块
 // @Summary 触发紧急求助
-// @Description 用户触发SOS紧急求助
+// @Description 用户触发SOS紧急求助，监护人可以通过 user_id 代老人求助
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
@@ -40,13 +58,17 @@ func (sc *SOSController) TriggerEmergency(c *gin.Context) {
 		c.JSON(http.StatusOK, vo.Fail(constants.PARAM_ERROR))
 		return
 	}
+	userID, ok := actingOwner(c, sc.accessService, req.UserID)
+	if !ok {
+		return
+	}
 
 	// 开始事务
 	tx := global.Db.Begin()
 
 	// 创建紧急任务
 	task := models.Task{
-		CreatorID:   req.UserID,
+		CreatorID:   userID,
 		Title:       "紧急求助",
 		Description: req.Description,
 		Category:    "emergency",
@@ -64,16 +86,16 @@ func (sc *SOSController) TriggerEmergency(c *gin.Context) {
 	}
 
 	// 创建SOS记录
-	timeoutAt := time.Now().Add(5 * time.Minute)
+	timeoutAt := time.Now().Add(constants.SOS_RESPONSE_TIMEOUT)
 	sosRecord := models.SOSRecord{
-		UserID:      req.UserID,
+		UserID:      userID,
 		TaskID:      task.ID,
 		Latitude:    req.Latitude,
 		Longitude:   req.Longitude,
 		Address:     req.Address,
 		Description: req.Description,
 		Severity:    req.Severity,
-		Status:      "pending",
+		Status:      constants.SOS_STATUS_PENDING,
 		TimeoutAt:   &timeoutAt,
 	}
 
@@ -90,14 +112,20 @@ func (sc *SOSController) TriggerEmergency(c *gin.Context) {
 		return
 	}
 
+	// 先通知老人的监护人，再通知陌生志愿者
+	sc.notifyGuardians(&sosRecord)
+
 	// 执行紧急匹配
-	matches, _ := sc.matchingService.MatchEmergencyVolunteers(task.ID, req.Latitude, req.Longitude)
+	matches, _ := sc.matchingService.MatchEmergencyVolunteers(sosRecord.UserID, req.Latitude, req.Longitude)
+
+	// 推送告警给匹配到的志愿者，离线的上线后补发
+	sc.chatManager.NotifyVolunteers(&sosRecord, matches)
 
 	c.JSON(http.StatusOK, vo.Success(vo.SOSResponseVO{
 		SOSID:   sosRecord.ID,
 		TaskID:  task.ID,
 		Matches: matches,
-		Timeout: 300, // 5分钟
+		Timeout: int(constants.SOS_RESPONSE_TIMEOUT.Seconds()),
 	}))
 }
 
@@ -115,11 +143,19 @@ func (sc *SOSController) AcceptSOS(c *gin.Context) {
 	sosIDStr := c.Param("sosId")
 	sosID, _ := strconv.ParseUint(sosIDStr, 10, 32)
 
+	// 请求体可以为空
 	var req dto.AcceptSOSRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusOK, vo.Fail(constants.PARAM_ERROR))
 		return
 	}
+	volunteerID, ok := actingAccount(c, req.VolunteerID)
+	if !ok {
+		return
+	}
+	if !requireVerifiedVolunteer(c, sc.verificationService, volunteerID) {
+		return
+	}
 
 	// 获取SOS记录
 	var sosRecord models.SOSRecord
@@ -128,7 +164,13 @@ func (sc *SOSController) AcceptSOS(c *gin.Context) {
 		return
 	}
 
-	if sosRecord.Status != "pending" {
+	// 超时升级中的SOS仍然可以被接受
+	switch sosRecord.Status {
+	case constants.SOS_STATUS_PENDING, constants.SOS_STATUS_MATCHING, constants.SOS_STATUS_ESCALATED:
+	case constants.SOS_STATUS_ACCEPTED, constants.SOS_STATUS_IN_PROGRESS:
+		c.JSON(http.StatusOK, vo.Fail(constants.TASK_ALREADY_ACCEPTED))
+		return
+	default:
 		c.JSON(http.StatusOK, vo.Fail(constants.SOS_ALREADY_RESOLVED))
 		return
 	}
@@ -136,21 +178,32 @@ func (sc *SOSController) AcceptSOS(c *gin.Context) {
 	// 开始事务
 	tx := global.Db.Begin()
 
-	// 更新SOS记录状态
-	if err := tx.Model(&sosRecord).Updates(map[string]interface{}{
-		"status": "accepted",
-	}).Error; err != nil {
+	// 更新SOS记录状态，以可接受状态为条件，多名志愿者同时接受时只有一人成功
+	result := tx.Model(&models.SOSRecord{}).
+		Where("id = ? AND status IN (?)", sosRecord.ID, []string{constants.SOS_STATUS_PENDING,
+			constants.SOS_STATUS_MATCHING, constants.SOS_STATUS_ESCALATED}).
+		Updates(map[string]interface{}{
+			"status":     constants.SOS_STATUS_ACCEPTED,
+			"timeout_at": nil,
+		})
+	if result.Error != nil {
 		tx.Rollback()
 		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
 		return
 	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusOK, vo.Fail(constants.TASK_ALREADY_ACCEPTED))
+		return
+	}
 
 	// 更新关联的任务状态
-	if err := tx.Model(&models.Task{}).Where("id = ?", sosRecord.TaskID).Updates(map[string]interface{}{
-		"assignee_id": req.VolunteerID,
-		"status":      "assigned",
-	}).Error; err != nil {
+	if _, err := sc.lifecycleService.TransitionInTx(tx, sosRecord.TaskID, services.AcceptTransition(volunteerID, "志愿者响应SOS")); err != nil {
 		tx.Rollback()
+		if errors.Is(err, server_error.TaskInvalidTransitionError) {
+			c.JSON(http.StatusOK, vo.Fail(constants.TASK_ALREADY_ACCEPTED))
+			return
+		}
 		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
 		return
 	}
@@ -162,12 +215,25 @@ func (sc *SOSController) AcceptSOS(c *gin.Context) {
 		return
 	}
 
+	// 通知求助者已有志愿者响应
+	var volunteer models.Account
+	global.Db.Select("id", "nickname").First(&volunteer, volunteerID)
+	acceptedEvent := vo.SOSStatusEventVO{
+		SOSID:       sosRecord.ID,
+		TaskID:      sosRecord.TaskID,
+		Status:      constants.SOS_STATUS_ACCEPTED,
+		VolunteerID: volunteerID,
+		Nickname:    volunteer.Nickname,
+	}
+	sc.chatManager.PushEvent(sosRecord.UserID, constants.WS_EVENT_SOS_ACCEPTED, acceptedEvent)
+	sc.publisher.Publish(services.SOSTopic(sosRecord.ID), constants.WS_EVENT_SOS_ACCEPTED, acceptedEvent)
+
 	c.JSON(http.StatusOK, vo.Success(nil))
 }
 
 // @Tags SOS模块
 // @Summary 解决SOS求助
-// @Description 标记SOS紧急求助为已解决
+// @Description 求助人、接单志愿者、已确认的监护人或管理员标记SOS紧急求助为已解决
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
@@ -179,11 +245,16 @@ func (sc *SOSController) ResolveSOS(c *gin.Context) {
 	sosIDStr := c.Param("sosId")
 	sosID, _ := strconv.ParseUint(sosIDStr, 10, 32)
 
+	// 请求体可以为空
 	var req dto.ResolveSOSRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusOK, vo.Fail(constants.PARAM_ERROR))
 		return
 	}
+	resolvedBy, ok := actingAccount(c, req.ResolvedBy)
+	if !ok {
+		return
+	}
 
 	// 获取SOS记录
 	var sosRecord models.SOSRecord
@@ -191,8 +262,11 @@ func (sc *SOSController) ResolveSOS(c *gin.Context) {
 		c.JSON(http.StatusOK, vo.Fail(constants.SOS_NOT_EXIST))
 		return
 	}
+	if !sc.requireSOSAccess(c, &sosRecord, sc.accessService.CanHandle) {
+		return
+	}
 
-	if sosRecord.Status == "resolved" {
+	if sosRecord.Status == constants.SOS_STATUS_RESOLVED {
 		c.JSON(http.StatusOK, vo.Fail(constants.SOS_ALREADY_RESOLVED))
 		return
 	}
@@ -203,7 +277,7 @@ func (sc *SOSController) ResolveSOS(c *gin.Context) {
 	now := time.Now()
 	// 更新SOS记录状态
 	if err := tx.Model(&sosRecord).Updates(map[string]interface{}{
-		"status":      "resolved",
+		"status":      constants.SOS_STATUS_RESOLVED,
 		"resolved_at": &now,
 	}).Error; err != nil {
 		tx.Rollback()
@@ -227,6 +301,15 @@ func (sc *SOSController) ResolveSOS(c *gin.Context) {
 		return
 	}
 
+	resolvedEvent := vo.SOSStatusEventVO{
+		SOSID:       sosRecord.ID,
+		TaskID:      sosRecord.TaskID,
+		Status:      constants.SOS_STATUS_RESOLVED,
+		VolunteerID: resolvedBy,
+	}
+	sc.chatManager.PushEvent(sosRecord.UserID, constants.WS_EVENT_SOS_RESOLVED, resolvedEvent)
+	sc.publisher.Publish(services.SOSTopic(sosRecord.ID), constants.WS_EVENT_SOS_RESOLVED, resolvedEvent)
+
 	c.JSON(http.StatusOK, vo.Success(nil))
 }
 
@@ -242,16 +325,27 @@ func (sc *SOSController) GetCurrentSOS(c *gin.Context) {
 	// 获取当前用户ID
 	userID := utils.GetAccountIdInContext(c)
 
-	var sosRecord models.SOSRecord
-	if err := global.Db.Where("user_id = ? AND status IN (?)", userID, []string{"pending", "matching", "accepted", "in_progress"}).
-		Order("created_at DESC").
-		First(&sosRecord).Error; err != nil {
+	sosVO, err := findCurrentSOS(userID)
+	if err != nil {
 		// 没有进行中的SOS是正常的
 		c.JSON(http.StatusOK, vo.Success(nil))
 		return
 	}
 
-	sosVO := vo.SOSRecordVO{
+	c.JSON(http.StatusOK, vo.Success(sosVO))
+}
+
+// 查询用户进行中的SOS，监护人查看老人状态时同样使用
+func findCurrentSOS(userID uint) (*vo.SOSRecordVO, error) {
+	var sosRecord models.SOSRecord
+	if err := global.Db.Where("user_id = ? AND status IN (?)", userID, []string{constants.SOS_STATUS_PENDING, constants.SOS_STATUS_MATCHING,
+		constants.SOS_STATUS_ACCEPTED, constants.SOS_STATUS_IN_PROGRESS, constants.SOS_STATUS_ESCALATED}).
+		Order("created_at DESC").
+		First(&sosRecord).Error; err != nil {
+		return nil, err
+	}
+
+	return &vo.SOSRecordVO{
 		ID:          sosRecord.ID,
 		UserID:      sosRecord.UserID,
 		TaskID:      sosRecord.TaskID,
@@ -262,7 +356,81 @@ func (sc *SOSController) GetCurrentSOS(c *gin.Context) {
 		Severity:    sosRecord.Severity,
 		Status:      sosRecord.Status,
 		CreatedAt:   sosRecord.CreatedAt,
+	}, nil
+}
+
+// @Tags SOS模块
+// @Summary 获取SOS升级记录
+// @Description 获取SOS超时后的每一次升级处理记录，限求助人、接单人、求助人已确认的监护人和管理员查看
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param sosId path int true "SOS记录ID"
+// @Success 200 {object} vo.ResponseVO{data=[]models.SOSEscalationLog}
+// @Router /sos/{sosId}/escalations [get]
+func (sc *SOSController) GetEscalations(c *gin.Context) {
+	sosID, err := strconv.ParseUint(c.Param("sosId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.PARAM_ERROR))
+		return
 	}
 
-	c.JSON(http.StatusOK, vo.Success(sosVO))
+	var sosRecord models.SOSRecord
+	if err := global.Db.First(&sosRecord, uint(sosID)).Error; err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.SOS_NOT_EXIST))
+		return
+	}
+	if !sc.requireSOSAccess(c, &sosRecord, sc.accessService.CanView) {
+		return
+	}
+
+	logs, err := sc.escalationService.GetEscalationLogs(sosRecord.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+
+	c.JSON(http.StatusOK, vo.Success(logs))
+}
+
+// 校验当前用户对SOS的权限，没有权限时直接写入响应
+func (sc *SOSController) requireSOSAccess(c *gin.Context, sosRecord *models.SOSRecord,
+	check func(accountID uint, role string, ownerID uint, assigneeID *uint) (bool, error)) bool {
+	assigneeID, err := sc.accessService.SOSAssignee(sosRecord)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
+		return false
+	}
+	allowed, err := check(utils.GetAccountIdInContext(c), utils.GetRoleInContext(c), sosRecord.UserID, assigneeID)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusOK, vo.Fail(constants.NO_PERMISSION))
+		return false
+	}
+	return true
+}
+
+// 按优先级推送SOS告警给已确认的监护人
+func (sc *SOSController) notifyGuardians(sosRecord *models.SOSRecord) {
+	guardianIDs, err := sc.guardianService.GetVerifiedGuardianIDs(sosRecord.UserID)
+	if err != nil {
+		global.Logger.Error("get guardians error", zap.Uint("sos_id", sosRecord.ID), zap.Error(err))
+		return
+	}
+	for _, guardianID := range guardianIDs {
+		sc.chatManager.PushEvent(guardianID, constants.WS_EVENT_SOS_GUARDIAN_ALERT, vo.SOSAlertVO{
+			SOSID:       sosRecord.ID,
+			TaskID:      sosRecord.TaskID,
+			UserID:      sosRecord.UserID,
+			Latitude:    sosRecord.Latitude,
+			Longitude:   sosRecord.Longitude,
+			Address:     sosRecord.Address,
+			Description: sosRecord.Description,
+			Severity:    sosRecord.Severity,
+			TimeoutAt:   sosRecord.TimeoutAt,
+		})
+	}
 }
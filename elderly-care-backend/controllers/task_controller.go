@@ -2,32 +2,64 @@ package controllers
 
 import (
 	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/server_error"
 	"elderly-care-backend/dto"
 	"elderly-care-backend/global"
 	"elderly-care-backend/models"
 	"elderly-care-backend/services"
+	"elderly-care-backend/utils"
 	"elderly-care-backend/vo"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type TaskController struct {
-	matchingService *services.TaskMatchingService
+	matchingService     *services.TaskMatchingService
+	lifecycleService    *services.TaskLifecycleService
+	verificationService *services.VolunteerVerificationService
+	accessService       *services.TaskAccessService
+	groupService        *services.ChatGroupService
+	publisher           services.TopicPublisher
 }
 
-func NewTaskController() *TaskController {
+func NewTaskController(publisher services.TopicPublisher) *TaskController {
 	return &TaskController{
-		matchingService: &services.TaskMatchingService{},
+		matchingService:     &services.TaskMatchingService{},
+		lifecycleService:    services.NewTaskLifecycleService(global.Db),
+		verificationService: services.NewVolunteerVerificationService(global.Db),
+		accessService:       services.NewTaskAccessService(global.Db),
+		groupService:        services.NewChatGroupService(global.Db),
+		publisher:           publisher,
 	}
 }
 
+// 任务和求助的发布人：默认是当前登录用户，请求体指定其他人时当前用户必须是他已确认的监护人
+func actingOwner(c *gin.Context, accessService *services.TaskAccessService, ownerID uint) (uint, bool) {
+	accountID := utils.GetAccountIdInContext(c)
+	if ownerID == 0 {
+		return accountID, true
+	}
+	allowed, err := accessService.CanActFor(accountID, ownerID)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
+		return 0, false
+	}
+	if !allowed {
+		c.JSON(http.StatusOK, vo.Fail(constants.NO_PERMISSION))
+		return 0, false
+	}
+	return ownerID, true
+}
+
 // @Tags 任务模块
 // @Summary 创建任务
-// @Description 创建新的求助任务
+// @Description 创建新的求助任务，监护人可以通过 creator_id 代老人发布
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
@@ -41,9 +73,24 @@ func (tc *TaskController) CreateTask(c *gin.Context) {
 		return
 	}
 
-	// 创建任务 - 修复CreatorID类型
+	// 截止时间可选，填写时必须晚于当前时间
+	var deadline *time.Time
+	if req.Deadline != nil && *req.Deadline != "" {
+		t, err := utils.ParseTime(*req.Deadline)
+		if err != nil || !t.After(time.Now()) {
+			c.JSON(http.StatusOK, vo.Fail(constants.TASK_DEADLINE_INVALID))
+			return
+		}
+		deadline = &t
+	}
+
+	creatorID, ok := actingOwner(c, tc.accessService, req.CreatorID)
+	if !ok {
+		return
+	}
+
 	task := models.Task{
-		CreatorID:   uint(req.CreatorID), // 转换为uint
+		CreatorID:   creatorID,
 		Title:       req.Title,
 		Description: req.Description,
 		Category:    req.Category,
@@ -51,6 +98,7 @@ func (tc *TaskController) CreateTask(c *gin.Context) {
 		Latitude:    req.Latitude,
 		Longitude:   req.Longitude,
 		Address:     req.Address,
+		Deadline:    deadline,
 	}
 
 	if err := global.Db.Create(&task).Error; err != nil {
@@ -73,6 +121,7 @@ func (tc *TaskController) CreateTask(c *gin.Context) {
 		Latitude:    task.Latitude,
 		Longitude:   task.Longitude,
 		Address:     task.Address,
+		Deadline:    task.Deadline,
 		CreatedAt:   task.CreatedAt,
 	}
 
@@ -124,13 +173,14 @@ func (tc *TaskController) GetNearbyTasks(c *gin.Context) {
             CASE WHEN t.category = 'emergency' THEN 1 ELSE 0 END as is_emergency
         FROM task t
         WHERE t.status = 'pending'
+          AND (t.deadline IS NULL OR t.deadline > ?)
           AND (6371000 * ACOS(
                 COS(? * PI() / 180) * COS(t.latitude * PI() / 180) * 
                 COS((t.longitude - ?) * PI() / 180) + 
                 SIN(? * PI() / 180) * SIN(t.latitude * PI() / 180)
             )) < ?`
 
-	params := []interface{}{lat, lng, lat, lat, lng, lat, radius}
+	params := []interface{}{lat, lng, lat, time.Now(), lat, lng, lat, radius}
 	if category != "" && category != "all" {
 		query += " AND t.category = ?"
 		params = append(params, category)
@@ -168,66 +218,366 @@ func (tc *TaskController) AcceptTask(c *gin.Context) {
 
 	var req dto.AcceptTaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("参数绑定错误: %v\n", err)
 		c.JSON(http.StatusOK, vo.Fail(constants.PARAM_ERROR))
 		return
 	}
 
-	// 1. 从 JWT 中获取用户ID（安全增强）
-	userID, exists := c.Get("userID")
-	if exists {
-		// 如果 JWT 中有用户ID，使用 JWT 中的ID（更安全）
-		jwtUserID := userID.(uint)
-		fmt.Printf("从JWT获取用户ID: %d\n", jwtUserID)
-		// 这里可以选择使用 JWT 中的用户ID，或者验证与请求体中的ID是否一致
+	// 接单人就是当前登录的志愿者
+	volunteerID, ok := actingAccount(c, req.VolunteerID)
+	if !ok {
+		return
 	}
-
-	// 2. 检查志愿者是否存在（数据验证）
-	var volunteer models.Account
-	if err := global.Db.First(&volunteer, req.VolunteerID).Error; err != nil {
-		c.JSON(http.StatusOK, vo.Fail("志愿者用户不存在"))
+	if !requireVerifiedVolunteer(c, tc.verificationService, volunteerID) {
 		return
 	}
 
 	// 获取任务详情
 	var task models.Task
 	if err := global.Db.First(&task, uint(taskID)).Error; err != nil {
-		fmt.Printf("任务查询错误: %v\n", err)
 		c.JSON(http.StatusOK, vo.Fail(constants.TASK_NOT_EXIST))
 		return
 	}
 
-	fmt.Printf("找到任务: ID=%d, Status=%s, CreatorID=%d\n", task.ID, task.Status, task.CreatorID)
-
-	if task.Status != "pending" {
-		fmt.Printf("任务状态不是pending: %s\n", task.Status)
+	if task.Status != constants.TASK_STATUS_PENDING {
 		c.JSON(http.StatusOK, vo.Fail(constants.TASK_ALREADY_ACCEPTED))
 		return
 	}
 
-	// 2. 检查志愿者不能接受自己的任务（数据验证）
-	if task.CreatorID == req.VolunteerID {
+	// 检查志愿者不能接受自己的任务
+	if task.CreatorID == volunteerID {
 		c.JSON(http.StatusOK, vo.Fail("不能接受自己的任务"))
 		return
 	}
 
 	// 更新任务状态
-	fmt.Printf("准备更新任务: assignee_id=%d, status=assigned\n", req.VolunteerID)
-	if err := global.Db.Model(&task).Updates(map[string]interface{}{
-		"assignee_id": uint(req.VolunteerID),
-		"status":      "assigned",
-	}).Error; err != nil {
-		// 1. 详细的错误处理
-		if strings.Contains(err.Error(), "foreign key constraint") {
-			fmt.Printf("外键约束错误: %v\n", err)
-			c.JSON(http.StatusOK, vo.Fail("志愿者用户不存在"))
+	accepted, err := tc.lifecycleService.Transition(task.ID, services.AcceptTransition(volunteerID, "志愿者接单"))
+	if err != nil {
+		if errors.Is(err, server_error.TaskInvalidTransitionError) {
+			c.JSON(http.StatusOK, vo.Fail(constants.TASK_ALREADY_ACCEPTED))
 			return
 		}
-		fmt.Printf("数据库更新错误: %v\n", err)
+		global.Logger.Error("accept task error", zap.Uint("task_id", task.ID), zap.Error(err))
 		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
 		return
 	}
 
-	fmt.Printf("任务接受成功! 任务ID=%d 被志愿者ID=%d 接受\n", task.ID, req.VolunteerID)
+	tc.publishStatus(accepted)
+	tc.syncTaskGroup(accepted, nil)
 	c.JSON(http.StatusOK, vo.Success(nil))
 }
+
+// @Tags 任务模块
+// @Summary 开始任务
+// @Description 接单志愿者开始执行任务
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param taskId path int true "任务ID"
+// @Success 200 {object} vo.ResponseVO
+// @Router /tasks/{taskId}/start [post]
+func (tc *TaskController) StartTask(c *gin.Context) {
+	operatorID := utils.GetAccountIdInContext(c)
+	tc.transition(c, services.TaskTransition{
+		To:         constants.TASK_STATUS_IN_PROGRESS,
+		OperatorID: operatorID,
+		Reason:     "志愿者开始执行",
+		Guard:      services.RequireAssignee(operatorID),
+	})
+}
+
+// @Tags 任务模块
+// @Summary 完成任务
+// @Description 接单志愿者或发布人确认任务完成
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param taskId path int true "任务ID"
+// @Success 200 {object} vo.ResponseVO
+// @Router /tasks/{taskId}/complete [post]
+func (tc *TaskController) CompleteTask(c *gin.Context) {
+	operatorID := utils.GetAccountIdInContext(c)
+	tc.transition(c, services.TaskTransition{
+		To:         constants.TASK_STATUS_COMPLETED,
+		OperatorID: operatorID,
+		Reason:     "任务完成",
+		Guard: func(task *models.Task) error {
+			if task.CreatorID == operatorID {
+				return nil
+			}
+			return services.RequireAssignee(operatorID)(task)
+		},
+	})
+}
+
+// @Tags 任务模块
+// @Summary 取消任务
+// @Description 发布人取消尚未完成的任务
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param taskId path int true "任务ID"
+// @Param request body dto.TaskTransitionRequest false "取消原因"
+// @Success 200 {object} vo.ResponseVO
+// @Router /tasks/{taskId}/cancel [post]
+func (tc *TaskController) CancelTask(c *gin.Context) {
+	var req dto.TaskTransitionRequest
+	_ = c.ShouldBindJSON(&req)
+	operatorID := utils.GetAccountIdInContext(c)
+	var previousAssigneeID *uint
+	task := tc.transition(c, services.TaskTransition{
+		To:         constants.TASK_STATUS_CANCELLED,
+		OperatorID: operatorID,
+		Reason:     utils.WithDefault(req.Reason, "发布人取消"),
+		Guard: func(task *models.Task) error {
+			if err := services.RequireCreator(operatorID)(task); err != nil {
+				return err
+			}
+			previousAssigneeID = task.AssigneeID
+			return nil
+		},
+	})
+	if task != nil {
+		tc.syncTaskGroup(task, previousAssigneeID)
+	}
+}
+
+// @Tags 任务模块
+// @Summary 放弃任务
+// @Description 接单志愿者放弃任务，任务重新回到待接单状态
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param taskId path int true "任务ID"
+// @Param request body dto.TaskTransitionRequest false "放弃原因"
+// @Success 200 {object} vo.ResponseVO
+// @Router /tasks/{taskId}/abandon [post]
+func (tc *TaskController) AbandonTask(c *gin.Context) {
+	var req dto.TaskTransitionRequest
+	_ = c.ShouldBindJSON(&req)
+	operatorID := utils.GetAccountIdInContext(c)
+	task := tc.transition(c, services.TaskTransition{
+		To:         constants.TASK_STATUS_PENDING,
+		OperatorID: operatorID,
+		Reason:     utils.WithDefault(req.Reason, "志愿者放弃"),
+		Updates: map[string]interface{}{
+			"assignee_id": nil,
+		},
+		Guard: services.RequireAssignee(operatorID),
+	})
+	if task != nil {
+		tc.syncTaskGroup(task, &operatorID)
+	}
+}
+
+// @Tags 任务模块
+// @Summary 改派任务
+// @Description 发布人将已接单的任务改派给其他志愿者
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param taskId path int true "任务ID"
+// @Param request body dto.ReassignTaskRequest true "改派请求参数"
+// @Success 200 {object} vo.ResponseVO
+// @Router /tasks/{taskId}/reassign [post]
+func (tc *TaskController) ReassignTask(c *gin.Context) {
+	var req dto.ReassignTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	var volunteer models.Account
+	if err := global.Db.First(&volunteer, req.VolunteerID).Error; err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.ACCOUNT_NOT_EXIST))
+		return
+	}
+	if !requireVerifiedVolunteer(c, tc.verificationService, req.VolunteerID) {
+		return
+	}
+
+	operatorID := utils.GetAccountIdInContext(c)
+	var previousAssigneeID *uint
+	task := tc.transition(c, services.TaskTransition{
+		To:         constants.TASK_STATUS_ASSIGNED,
+		OperatorID: operatorID,
+		Reason:     utils.WithDefault(req.Reason, "发布人改派"),
+		Updates: map[string]interface{}{
+			"assignee_id": req.VolunteerID,
+		},
+		Guard: func(task *models.Task) error {
+			if err := services.RequireCreator(operatorID)(task); err != nil {
+				return err
+			}
+			// 只能改派已有接单人的任务，不能改派给发布人自己或当前接单人
+			if task.AssigneeID == nil || req.VolunteerID == task.CreatorID || req.VolunteerID == *task.AssigneeID {
+				return server_error.TaskInvalidTransitionError
+			}
+			previousAssigneeID = task.AssigneeID
+			return nil
+		},
+	})
+	if task != nil {
+		tc.syncTaskGroup(task, previousAssigneeID)
+	}
+}
+
+// @Tags 任务模块
+// @Summary 任务状态记录
+// @Description 获取任务的状态变更记录，限发布人、接单人、发布人已确认的监护人和管理员查看
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param taskId path int true "任务ID"
+// @Success 200 {object} vo.ResponseVO{data=[]models.TaskStatusHistory}
+// @Router /tasks/{taskId}/history [get]
+func (tc *TaskController) GetTaskHistory(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("taskId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	var task models.Task
+	if err := global.Db.Select("id", "creator_id", "assignee_id").First(&task, uint(taskID)).Error; err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.TASK_NOT_EXIST))
+		return
+	}
+	allowed, err := tc.accessService.CanView(utils.GetAccountIdInContext(c), utils.GetRoleInContext(c), task.CreatorID, task.AssigneeID)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusOK, vo.Fail(constants.NO_PERMISSION))
+		return
+	}
+
+	history, err := tc.lifecycleService.GetHistory(task.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+
+	c.JSON(http.StatusOK, vo.Success(history))
+}
+
+// 执行状态流转并返回统一的结果，失败时返回 nil
+func (tc *TaskController) transition(c *gin.Context, transition services.TaskTransition) *models.Task {
+	taskID, err := strconv.ParseUint(c.Param("taskId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.PARAM_ERROR))
+		return nil
+	}
+
+	task, err := tc.lifecycleService.Transition(uint(taskID), transition)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(taskErrorMsg(err)))
+		return nil
+	}
+	tc.publishStatus(task)
+
+	c.JSON(http.StatusOK, vo.Success(gin.H{
+		"task_id": task.ID,
+		"status":  task.Status,
+	}))
+	return task
+}
+
+// 把状态变更发布到任务主题，发布人、接单人和发布人的监护人订阅后可以实时收到
+func (tc *TaskController) publishStatus(task *models.Task) {
+	tc.publisher.Publish(services.TaskTopic(task.ID), constants.WS_EVENT_TASK_STATUS, vo.TaskStatusEventVO{
+		TaskID:   task.ID,
+		Title:    task.Title,
+		Status:   task.Status,
+		Deadline: task.Deadline,
+	})
+}
+
+// 接单和改派后把发布人、接单人和监护人拉进任务群，放弃和取消后把原接单人移出，失败不影响任务本身
+func (tc *TaskController) syncTaskGroup(task *models.Task, previousAssigneeID *uint) {
+	var err error
+	if task.Status == constants.TASK_STATUS_ASSIGNED {
+		err = tc.groupService.EnsureTaskGroup(task, previousAssigneeID)
+	} else if previousAssigneeID != nil {
+		err = tc.groupService.LeaveTaskGroup(task, *previousAssigneeID)
+	}
+	if err != nil {
+		global.Logger.Warn("sync task chat group error", zap.Uint("task_id", task.ID), zap.Error(err))
+	}
+}
+
+// 将状态流转错误转换为错误码
+func taskErrorMsg(err error) string {
+	switch {
+	case errors.Is(err, server_error.TaskNotExistError),
+		errors.Is(err, server_error.TaskInvalidTransitionError),
+		errors.Is(err, server_error.TaskNotAssigneeError),
+		errors.Is(err, server_error.TaskNotCreatorError):
+		return err.Error()
+	default:
+		return constants.SERVICE_ERROR
+	}
+}
+
+// @Tags 任务模块
+// @Summary 延长截止时间
+// @Description 发布人延长未结束任务的截止时间
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param taskId path int true "任务ID"
+// @Param request body dto.ExtendDeadlineRequest true "新的截止时间"
+// @Success 200 {object} vo.ResponseVO
+// @Router /tasks/{taskId}/deadline [put]
+func (tc *TaskController) ExtendDeadline(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("taskId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	var req dto.ExtendDeadlineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+	deadline, err := utils.ParseTime(req.Deadline)
+	if err != nil || !deadline.After(time.Now()) {
+		c.JSON(http.StatusOK, vo.Fail(constants.TASK_DEADLINE_INVALID))
+		return
+	}
+
+	var task models.Task
+	if err := global.Db.First(&task, uint(taskID)).Error; err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.TASK_NOT_EXIST))
+		return
+	}
+	if task.CreatorID != utils.GetAccountIdInContext(c) {
+		c.JSON(http.StatusOK, vo.Fail(constants.TASK_NOT_CREATOR))
+		return
+	}
+	// 只能延长，不能提前
+	if task.Deadline != nil && !deadline.After(*task.Deadline) {
+		c.JSON(http.StatusOK, vo.Fail(constants.TASK_DEADLINE_INVALID))
+		return
+	}
+
+	// 已结束的任务不能再延期
+	result := global.Db.Model(&models.Task{}).
+		Where("id = ? AND status IN (?)", task.ID, []string{constants.TASK_STATUS_PENDING,
+			constants.TASK_STATUS_ASSIGNED, constants.TASK_STATUS_IN_PROGRESS}).
+		Update("deadline", deadline)
+	if result.Error != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusOK, vo.Fail(constants.TASK_INVALID_TRANSITION))
+		return
+	}
+
+	c.JSON(http.StatusOK, vo.Success(gin.H{
+		"task_id":  task.ID,
+		"deadline": deadline,
+	}))
+}
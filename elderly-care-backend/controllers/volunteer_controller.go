@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/factories"
+	"elderly-care-backend/common/server_error"
+	"elderly-care-backend/dto"
+	. "elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"elderly-care-backend/services"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type VolunteerController struct {
+	availabilityService *services.VolunteerAvailabilityService
+	verificationService *services.VolunteerVerificationService
+}
+
+func NewVolunteerController() *VolunteerController {
+	return &VolunteerController{
+		availabilityService: services.NewVolunteerAvailabilityService(Db),
+		verificationService: services.NewVolunteerVerificationService(Db),
+	}
+}
+
+// @Tags 志愿者模块
+// @Summary 提交实名认证
+// @Description 上传证件正反面照片提交认证，审核通过后才能接单；待审核或被驳回时可重新提交
+// @Accept mpfd
+// @Produce json
+// @Security ApiKeyAuth
+// @Param real_name formData string true "真实姓名"
+// @Param id_front formData file true "证件正面(jpg/png)"
+// @Param id_back formData file true "证件反面(jpg/png)"
+// @Success 200 {object} vo.ResponseVO{data=vo.VerificationVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /volunteer/verification [post]
+func (vc *VolunteerController) SubmitVerification(c *gin.Context) {
+	accountID := utils.GetAccountIdInContext(c)
+
+	realName := strings.TrimSpace(c.PostForm("real_name"))
+	if realName == "" || len([]rune(realName)) > 32 {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	frontObject, err := uploadVerificationDocument(c, accountID, "id_front")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(err.Error()))
+		return
+	}
+	backObject, err := uploadVerificationDocument(c, accountID, "id_back")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(err.Error()))
+		return
+	}
+
+	verification, err := vc.verificationService.Submit(accountID, realName, frontObject, backObject)
+	if err != nil {
+		if errors.Is(err, server_error.VerificationInvalidTransitionError) {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.VERIFICATION_INVALID_TRANSITION))
+		} else {
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(toVerificationVO(verification)))
+}
+
+// @Tags 志愿者模块
+// @Summary 获取认证状态
+// @Description 获取当前志愿者的实名认证状态，未提交时状态为空
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} vo.ResponseVO{data=vo.VerificationVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /volunteer/verification [get]
+func (vc *VolunteerController) GetVerification(c *gin.Context) {
+	accountID := utils.GetAccountIdInContext(c)
+
+	verification, err := vc.verificationService.GetVerification(accountID)
+	if err != nil {
+		if errors.Is(err, server_error.VerificationNotExistError) {
+			c.JSON(http.StatusOK, vo.Success(vo.VerificationVO{}))
+		} else {
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(toVerificationVO(verification)))
+}
+
+// @Tags 志愿者模块
+// @Summary 获取接单状态
+// @Description 获取当前志愿者的在岗状态、任务上限和每周服务时段
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} vo.ResponseVO{data=vo.AvailabilityVO} "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /volunteer/availability [get]
+func (vc *VolunteerController) GetAvailability(c *gin.Context) {
+	accountID := utils.GetAccountIdInContext(c)
+
+	availability, windows, err := vc.availabilityService.GetAvailability(accountID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	openTasks, err := vc.availabilityService.CountOpenTasks(accountID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+
+	c.JSON(http.StatusOK, vo.Success(vo.AvailabilityVO{
+		OnDuty:             availability.OnDuty,
+		MaxConcurrentTasks: availability.MaxConcurrentTasks,
+		OpenTasks:          int(openTasks),
+		Windows:            windows,
+	}))
+}
+
+// @Tags 志愿者模块
+// @Summary 上岗/下岗
+// @Description 下岗后不会被任务和SOS匹配到
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.DutyRequest true "在岗状态"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /volunteer/duty [put]
+func (vc *VolunteerController) SetDuty(c *gin.Context) {
+	accountID := utils.GetAccountIdInContext(c)
+
+	var req dto.DutyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	if err := vc.availabilityService.SetOnDuty(accountID, *req.OnDuty); err != nil {
+		c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// @Tags 志愿者模块
+// @Summary 设置服务时段
+// @Description 设置同时进行的任务上限和每周服务时段，时段整体替换，为空表示全天可服务
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body dto.AvailabilityRequest true "服务时段"
+// @Success 200 {object} vo.ResponseVO "成功"
+// @Failure 400 {object} vo.ResponseVO "失败"
+// @Router /volunteer/availability [put]
+func (vc *VolunteerController) UpdateAvailability(c *gin.Context) {
+	accountID := utils.GetAccountIdInContext(c)
+
+	var req dto.AvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, vo.Fail(constants.PARAM_ERROR))
+		return
+	}
+
+	if err := vc.availabilityService.UpdateAvailability(accountID, &req); err != nil {
+		if errors.Is(err, services.ErrAvailabilityWindow) {
+			c.JSON(http.StatusBadRequest, vo.Fail(constants.AVAILABILITY_WINDOW_ERROR))
+		} else {
+			c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, vo.Success(nil))
+}
+
+// 上传证件照片到私有桶，返回对象名，错误信息即错误码
+func uploadVerificationDocument(c *gin.Context, accountID uint, field string) (string, error) {
+	fileHeader, err := c.FormFile(field)
+	if err != nil {
+		return "", errors.New(constants.FILE_UPLOAD_ERROR)
+	}
+	if !utils.IsImageFile(fileHeader.Filename) {
+		return "", errors.New(constants.FILE_FORMAT_ERROR)
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", errors.New(constants.FILE_OPEN_ERROR)
+	}
+	defer file.Close()
+
+	objectName := fmt.Sprintf("%d/%s%s", accountID, uuid.NewString(), path.Ext(fileHeader.Filename))
+	client := factories.OssClientFactory.GetOssClient(factories.MINIO)
+	if _, err = client.Upload(factories.VERIFICATION_BUCKET, objectName, file, fileHeader.Size); err != nil {
+		return "", errors.New(constants.UPLOAD_ERROR)
+	}
+	return objectName, nil
+}
+
+func toVerificationVO(verification *models.VolunteerVerification) vo.VerificationVO {
+	return vo.VerificationVO{
+		Status:      verification.Status,
+		RealName:    verification.RealName,
+		Remark:      verification.Remark,
+		SubmittedAt: &verification.SubmittedAt,
+		ReviewedAt:  verification.ReviewedAt,
+	}
+}
+
+// 操作人取自登录身份，请求体里的 volunteer_id、resolved_by 传了就必须是本人
+func actingAccount(c *gin.Context, bodyID uint) (uint, bool) {
+	accountID := utils.GetAccountIdInContext(c)
+	if bodyID != 0 && bodyID != accountID {
+		c.JSON(http.StatusOK, vo.Fail(constants.NO_PERMISSION))
+		return 0, false
+	}
+	return accountID, true
+}
+
+// 只有通过实名认证的志愿者可以接单，未通过时直接写入响应
+func requireVerifiedVolunteer(c *gin.Context, verificationService *services.VolunteerVerificationService, volunteerID uint) bool {
+	verified, err := verificationService.IsVerified(volunteerID)
+	if err != nil {
+		c.JSON(http.StatusOK, vo.Fail(constants.SERVICE_ERROR))
+		return false
+	}
+	if !verified {
+		c.JSON(http.StatusOK, vo.Fail(constants.VERIFICATION_REQUIRED))
+		return false
+	}
+	return true
+}
@@ -7,5 +7,8 @@ import (
 type Claims struct {
 	AccountId uint   `json:"account_id"`
 	Nickname  string `json:"nickname"`
+	Role      string `json:"role"`
+	// 签发时的token版本号，修改密码、暂停账号后版本号递增，旧token失效
+	TokenVersion int64 `json:"tv"`
 	jwt.StandardClaims
 }
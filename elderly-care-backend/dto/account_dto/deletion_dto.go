@@ -0,0 +1,7 @@
+package account_dto
+
+// 申请注销账号，需要再次输入密码确认
+type DeletionRequestDTO struct {
+	Password string `json:"password" binding:"required"`
+	Reason   string `json:"reason" binding:"max=255"`
+}
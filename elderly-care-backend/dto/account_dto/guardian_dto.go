@@ -0,0 +1,12 @@
+package account_dto
+
+type GuardianAddDTO struct {
+	Phone        string `json:"phone" binding:"required"` // 监护人注册的手机号
+	Relationship string `json:"relationship"`
+	Priority     int    `json:"priority"`
+}
+
+type GuardianUpdateDTO struct {
+	Relationship string `json:"relationship"`
+	Priority     int    `json:"priority"`
+}
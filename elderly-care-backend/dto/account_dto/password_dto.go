@@ -0,0 +1,15 @@
+package account_dto
+
+type ResetVerifyDTO struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required"` // reset_password 验证码
+}
+
+type ResetPasswordDTO struct {
+	ResetToken  string `json:"reset_token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type AssistedResetDTO struct {
+	NewPassword string `json:"new_password" binding:"required"`
+}
@@ -12,5 +12,6 @@ type RegisterDTO struct {
 	Avatar   multipart.FileHeader `form:"avatar"`
 	Sex      models.Sex           `form:"sex"`
 	Age      int                  `form:"age"`
-	Role     string               `form:"role"`
+	Role     string               `form:"role"`     // elderly, volunteer, guardian，管理员账号不能自行注册
+	SmsCode  string               `form:"sms_code"` // 可选，填写时校验手机号并标记为已验证
 }
@@ -0,0 +1,15 @@
+package account_dto
+
+type SmsCodeDTO struct {
+	Phone   string `json:"phone" binding:"required"`
+	Purpose string `json:"purpose" binding:"required"` // login, register, verify_phone, reset_password
+}
+
+type SmsLoginDTO struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+type VerifyPhoneDTO struct {
+	Code string `json:"code" binding:"required"`
+}
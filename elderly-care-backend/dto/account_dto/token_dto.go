@@ -0,0 +1,10 @@
+package account_dto
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutDTO struct {
+	RefreshToken string `json:"refresh_token"` // 同时作废当前设备的刷新token
+	All          bool   `json:"all"`           // 退出所有设备
+}
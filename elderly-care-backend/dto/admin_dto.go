@@ -0,0 +1,5 @@
+package dto
+
+type SuspendAccountRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
@@ -5,9 +5,46 @@ import (
 )
 
 // MessageDTO 消息传输模型
-// @Description 前端发送消息使用的数据结构
+// @Description 前端发送消息使用的数据结构，单聊填 To，群聊填 GroupID；图片、文件和语音的 Content 为上传接口返回的地址
 type MessageDTO struct {
-	To      uint `gorm:"index:index_from_to"`
+	To      uint
+	GroupID uint
 	Content string
 	Type    models.MessageType
 }
+
+// ChatReceiptDTO 送达/已读回执，message_id 为空时确认到最新一条；群聊回执填 group_id
+type ChatReceiptDTO struct {
+	ContactID uint                 `json:"contact_id"`
+	GroupID   uint                 `json:"group_id"`
+	MessageID uint                 `json:"message_id"`
+	Status    models.MessageStatus `json:"status"`
+}
+
+// ChatReadDTO 标记会话已读，message_id 为空时整个会话已读
+type ChatReadDTO struct {
+	ContactID uint `json:"contact_id" binding:"required"`
+	MessageID uint `json:"message_id"`
+}
+
+// ChatMessageOpDTO 撤回、编辑或删除消息，content 只在编辑时使用
+type ChatMessageOpDTO struct {
+	MessageID uint   `json:"message_id"`
+	Content   string `json:"content"`
+}
+
+// EditChatMessageDTO 编辑文本消息
+type EditChatMessageDTO struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// CreateChatGroupDTO 创建群聊，member_ids 中的用户会收到邀请
+type CreateChatGroupDTO struct {
+	Name      string `json:"name" binding:"required,max=64"`
+	MemberIDs []uint `json:"member_ids" binding:"max=49"`
+}
+
+// InviteChatGroupDTO 邀请加入群聊
+type InviteChatGroupDTO struct {
+	AccountIDs []uint `json:"account_ids" binding:"required,min=1,max=49"`
+}
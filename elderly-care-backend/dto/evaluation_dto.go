@@ -0,0 +1,8 @@
+package dto
+
+type TaskEvaluationRequest struct {
+	TaskID  uint     `json:"task_id" binding:"required"`
+	Score   int      `json:"score" binding:"required,min=1,max=5"`
+	Comment string   `json:"comment" binding:"max=500"`
+	Tags    []string `json:"tags" binding:"max=5,dive,min=1,max=20"` // 最多5个标签，每个不超过20个字
+}
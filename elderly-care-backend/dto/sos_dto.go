@@ -1,7 +1,7 @@
 package dto
 
 type SOSRequest struct {
-	UserID      uint    `json:"user_id" binding:"required"` // 改为uint
+	UserID      uint    `json:"user_id"` // 可选，默认为当前登录用户，监护人代老人求助时填老人ID
 	Latitude    float64 `json:"latitude" binding:"required"`
 	Longitude   float64 `json:"longitude" binding:"required"`
 	Address     string  `json:"address" binding:"required"`
@@ -10,9 +10,9 @@ type SOSRequest struct {
 }
 
 type AcceptSOSRequest struct {
-	VolunteerID uint `json:"volunteer_id" binding:"required"` // 改为uint
+	VolunteerID uint `json:"volunteer_id"` // 可选，接单人取自登录身份，传入时必须是本人
 }
 
 type ResolveSOSRequest struct {
-	ResolvedBy uint `json:"resolved_by" binding:"required"` // 改为uint
+	ResolvedBy uint `json:"resolved_by"` // 可选，处理人取自登录身份，传入时必须是本人
 }
@@ -1,7 +1,7 @@
 package dto
 
 type CreateTaskRequest struct {
-	CreatorID   uint    `json:"creator_id" binding:"required"` // 改为uint
+	CreatorID   uint    `json:"creator_id"` // 可选，默认为当前登录用户，监护人代老人发布时填老人ID
 	Title       string  `json:"title" binding:"required"`
 	Description string  `json:"description" binding:"required"`
 	Category    string  `json:"category" binding:"required"`
@@ -12,8 +12,21 @@ type CreateTaskRequest struct {
 	Deadline    *string `json:"deadline,omitempty"`
 }
 
+type TaskTransitionRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ExtendDeadlineRequest struct {
+	Deadline string `json:"deadline" binding:"required"`
+}
+
+type ReassignTaskRequest struct {
+	VolunteerID uint   `json:"volunteer_id" binding:"required"`
+	Reason      string `json:"reason"`
+}
+
 type AcceptTaskRequest struct {
-	VolunteerID  uint    `json:"volunteer_id" binding:"required"` // 改为uint
+	VolunteerID  uint    `json:"volunteer_id"` // 可选，接单人取自登录身份，传入时必须是本人
 	VolunteerLat float64 `json:"volunteer_lat" binding:"required"`
 	VolunteerLng float64 `json:"volunteer_lng" binding:"required"`
 }
@@ -0,0 +1,21 @@
+package dto
+
+type DutyRequest struct {
+	OnDuty *bool `json:"on_duty" binding:"required"`
+}
+
+type AvailabilityWindowDTO struct {
+	Weekday   int    `json:"weekday" binding:"min=0,max=6"`
+	StartTime string `json:"start_time" binding:"required"` // HH:MM
+	EndTime   string `json:"end_time" binding:"required"`   // HH:MM
+}
+
+type AvailabilityRequest struct {
+	MaxConcurrentTasks int                     `json:"max_concurrent_tasks" binding:"required,min=1,max=10"`
+	Windows            []AvailabilityWindowDTO `json:"windows" binding:"dive"`
+}
+
+type VerificationReviewRequest struct {
+	Status string `json:"status" binding:"required,oneof=verified rejected suspended"`
+	Remark string `json:"remark" binding:"max=255"`
+}
@@ -24,6 +24,8 @@ func main() {
 
 	// 初始化oss工厂
 	factories.InitOssFactory()
+	// 初始化短信工厂
+	factories.InitSmsFactory()
 
 	// 初始化服务
 	initServices()
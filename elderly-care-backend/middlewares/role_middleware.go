@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/custom"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRoles 只允许指定角色访问，需在 VerifyMiddleware 之后使用
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	roleList := make([]interface{}, 0, len(roles))
+	for _, role := range roles {
+		roleList = append(roleList, role)
+	}
+	roleSet := custom.NewHashSet(roleList...)
+
+	return func(c *gin.Context) {
+		if !roleSet.Contains(utils.GetRoleInContext(c)) {
+			c.JSON(http.StatusForbidden, vo.Fail(constants.NO_PERMISSION))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"elderly-care-backend/common/constants"
 	"elderly-care-backend/common/custom"
 	"elderly-care-backend/config"
+	"elderly-care-backend/global"
+	"elderly-care-backend/services"
 	"elderly-care-backend/utils"
 	"elderly-care-backend/vo"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -19,9 +22,15 @@ func VerifyMiddleware() gin.HandlerFunc {
 		"/account/checkPhone",
 		"/music/list",
 		"/account/refresh",
+		"/account/sms/code",
+		"/account/login/sms",
+		"/account/password/reset/verify",
+		"/account/password/reset",
+		"/ws", // 实时网关自行校验 token，支持浏览器通过 ?token= 传递
 	}
 
 	whiteSet := custom.NewHashSet(whiteList...)
+	tokenService := services.NewTokenService(global.Db, global.RedisClient)
 
 	return func(c *gin.Context) {
 		// === 添加调试信息 ===
@@ -53,8 +62,21 @@ func VerifyMiddleware() gin.HandlerFunc {
 		}
 		fmt.Printf("Token验证成功，用户ID: %d\n", claims.AccountId)
 
+		// 已退出、修改密码或被暂停的账号，旧token不再可用
+		if err = tokenService.CheckAccess(c.Request.Context(), claims); err != nil {
+			if errors.Is(err, services.ErrTokenRevoked) {
+				c.JSON(http.StatusGone, vo.Fail(constants.TOKEN_REVOKED))
+			} else {
+				c.JSON(http.StatusBadGateway, vo.Fail(constants.SERVICE_ERROR))
+			}
+			c.Abort()
+			return
+		}
+
+		utils.SetClaimsInContext(c, claims)
 		utils.SetAccountIdInContext(c, claims.AccountId)
 		utils.SetNickNameInContext(c, claims.Nickname)
+		utils.SetRoleInContext(c, claims.Role)
 		c.Next()
 	}
 }
@@ -17,13 +17,18 @@ const (
 
 type Account struct {
 	BaseModel
-	Avatar   string `json:"avatar"`
-	Nickname string `gorm:"size:25;Index" json:"nickname"`
-	Sex      Sex    `json:"sex"`                              // 性别
-	Phone    string `gorm:"size:32;uniqueIndex" json:"phone"` // 手机号作为登录账号
-	Password string `gorm:"size:64" json:"-"`
-	Age      int    `json:"age"`
-	Role     string `gorm:"size:20;default:'user'" json:"role"` // 用户角色: user, volunteer
+	Avatar        string `json:"avatar"`
+	Nickname      string `gorm:"size:25;Index" json:"nickname"`
+	Sex           Sex    `json:"sex"`                                 // 性别
+	Phone         string `gorm:"size:32;uniqueIndex" json:"phone"`    // 手机号作为登录账号
+	PhoneVerified bool   `gorm:"default:false" json:"phone_verified"` // 是否通过短信验证码验证过手机号
+	Password      string `gorm:"size:64" json:"-"`
+	Age           int    `json:"age"`
+	Role          string `gorm:"size:20;default:'elderly'" json:"role"` // 用户角色: elderly, volunteer, guardian, admin, operator
+	// 账号状态，被管理员暂停后不能登录
+	Status        string     `gorm:"size:20;default:'active';index" json:"status"`
+	SuspendReason string     `gorm:"size:255" json:"suspend_reason,omitempty"`
+	SuspendedAt   *time.Time `json:"suspended_at,omitempty"`
 	// 新增位置相关字段
 	Latitude           float64    `gorm:"type:decimal(10,8)" json:"latitude"`
 	Longitude          float64    `gorm:"type:decimal(11,8)" json:"longitude"`
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// 账号注销申请，冷静期结束后由后台任务匿名化账号数据
+type AccountDeletion struct {
+	BaseModel
+	AccountID   uint       `gorm:"not null;index" json:"account_id"`
+	Reason      string     `gorm:"size:255" json:"reason"`
+	Status      string     `gorm:"size:20;index" json:"status"`
+	ScheduledAt time.Time  `gorm:"index" json:"scheduled_at"` // 冷静期结束、开始执行的时间
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+func (*AccountDeletion) TableName() string {
+	return "account_deletion"
+}
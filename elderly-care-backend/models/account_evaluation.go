@@ -1,10 +1,17 @@
 package models
 
+// 账号评价汇总，由 TaskEvaluation 重新计算得到
 type AccountEvaluation struct {
 	BaseModel
-	AccountID   uint    `gorm:"uniqueIndex"`
-	Score       float64 `json:"score"` // 总得分
-	AssignCount int     // 接收的任务数量
+	AccountID    uint    `gorm:"uniqueIndex"`
+	Score        float64 `json:"score"` // 总得分
+	AssignCount  int     // 收到评价的任务数量
+	AverageScore float64 `json:"average_score"` // 平均分
+	Star1Count   int     `json:"star1_count"`   // 各分值的评价数量
+	Star2Count   int     `json:"star2_count"`
+	Star3Count   int     `json:"star3_count"`
+	Star4Count   int     `json:"star4_count"`
+	Star5Count   int     `json:"star5_count"`
 }
 
 func (*AccountEvaluation) TableName() string {
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// 群聊，如老人、家属和常来的志愿者组成的照护圈；接单后也会为任务自动创建
+type ChatGroup struct {
+	BaseModel
+	Name          string `gorm:"size:64;not null" json:"name"`
+	OwnerID       uint   `gorm:"index" json:"owner_id"`
+	TaskID        *uint  `gorm:"uniqueIndex" json:"task_id,omitempty"` // 任务群对应的任务
+	LastMessageID uint   `json:"last_message_id"`
+}
+
+func (*ChatGroup) TableName() string {
+	return "chat_group"
+}
+
+// 群成员，被邀请后需要本人加入才能收发消息
+type ChatGroupMember struct {
+	BaseModel
+	GroupID            uint       `gorm:"uniqueIndex:unique_group_id_account_id" json:"group_id"`
+	AccountID          uint       `gorm:"uniqueIndex:unique_group_id_account_id;index" json:"account_id"`
+	Role               string     `gorm:"size:16" json:"role"`   // owner/member
+	Status             string     `gorm:"size:16" json:"status"` // invited/joined
+	InvitedBy          uint       `json:"invited_by"`
+	JoinedAt           *time.Time `json:"joined_at,omitempty"`
+	DeliveredMessageID uint       `json:"delivered_message_id"` // 群内已送达的最大消息ID
+	ReadMessageID      uint       `json:"read_message_id"`      // 群内已读的最大消息ID
+	UnreadCount        int        `json:"unread_count"`
+}
+
+func (*ChatGroupMember) TableName() string {
+	return "chat_group_member"
+}
@@ -4,9 +4,13 @@ import "time"
 
 type ContactList struct {
 	BaseModel
-	AccountID    uint      `gorm:"uniqueIndex:unique_account_id_contact_id"` //关联到某个账号
-	ContactID    uint      `gorm:"uniqueIndex:unique_account_id_contact_id"` //联系人账号ID
-	LastChatTime time.Time `gorm:"index"`                                    //上次聊天的时间
+	AccountID          uint      `gorm:"uniqueIndex:unique_account_id_contact_id"` //关联到某个账号
+	ContactID          uint      `gorm:"uniqueIndex:unique_account_id_contact_id"` //联系人账号ID
+	LastChatTime       time.Time `gorm:"index"`                                    //上次聊天的时间
+	LastMessageID      uint      //最后一条消息ID，用于会话列表的消息预览
+	UnreadCount        int       //对方发来的未读消息数
+	DeliveredMessageID uint      //对方发来的消息中已送达的最大ID，ID不超过它的都已送达
+	ReadMessageID      uint      //对方发来的消息中已读的最大ID
 }
 
 func (*ContactList) TableName() string {
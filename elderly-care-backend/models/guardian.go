@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// 监护人（紧急联系人）关系，老人发起邀请，监护人确认后生效
+type Guardian struct {
+	BaseModel
+	ElderID        uint       `gorm:"not null;uniqueIndex:unique_elder_id_guardian_id" json:"elder_id"`    // 被监护的老人
+	GuardianID     uint       `gorm:"not null;uniqueIndex:unique_elder_id_guardian_id" json:"guardian_id"` // 监护人账号
+	Relationship   string     `gorm:"size:20" json:"relationship"`                                         // 关系: 子女、配偶、亲属等
+	Priority       int        `gorm:"default:0" json:"priority"`                                           // 通知顺序，越小越优先
+	Status         string     `gorm:"size:20;default:'pending';index" json:"status"`
+	ElderConsentAt time.Time  `json:"elder_consent_at"` // 老人同意共享信息的时间
+	VerifiedAt     *time.Time `json:"verified_at"`      // 监护人确认关系的时间
+}
+
+func (*Guardian) TableName() string {
+	return "guardian"
+}
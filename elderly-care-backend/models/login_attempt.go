@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// 登录审计日志，成功和失败的登录都会记录
+type LoginAttempt struct {
+	ID        uint      `gorm:"primarykey;autoIncrement" json:"id"`
+	Phone     string    `gorm:"size:32;index" json:"phone"`
+	AccountID *uint     `gorm:"index" json:"account_id"` // 手机号未注册时为空
+	IP        string    `gorm:"size:64;index" json:"ip"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
+	Method    string    `gorm:"size:20" json:"method"` // password, sms
+	Success   bool      `json:"success"`
+	Reason    string    `gorm:"size:64" json:"reason"` // 失败原因，即返回的错误码
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+func (*LoginAttempt) TableName() string {
+	return "login_attempt"
+}
@@ -6,22 +6,50 @@ type MessageType uint8
 
 // MessageType 消息类型枚举
 // @Description 消息内容的类型
-// @Enum 0=text文本 1=image图片 2=file文件
+// @Enum 0=text文本 1=image图片 2=file文件 3=voice语音
 const (
 	Text MessageType = iota
 	Image
 	File
+	Voice
+)
+
+type MessageStatus uint8
+
+// MessageStatus 消息状态枚举
+// @Description 接收方的确认进度，只会前进
+// @Enum 0=sent已发送 1=delivered已送达 2=read已读
+const (
+	Sent MessageStatus = iota
+	Delivered
+	Read
 )
 
 type Message struct {
-	ID      uint      `gorm:"primarykey"`
-	Time    time.Time `gorm:"index;"`
-	From    uint      `gorm:"index:index_from_to"`
-	To      uint      `gorm:"index:index_from_to"`
-	Content string
-	Type    MessageType
+	ID         uint      `gorm:"primarykey"`
+	Time       time.Time `gorm:"index;"`
+	From       uint      `gorm:"index:index_from_to"`
+	To         uint      `gorm:"index:index_from_to"` // 群消息为 0
+	GroupID    uint      `gorm:"index"`               // 单聊为 0
+	Content    string
+	Type       MessageType
+	Status     MessageStatus `gorm:"default:0"` // 群消息的确认进度记录在群成员上
+	Duration   int           `gorm:"default:0"` // 语音时长(秒)，由服务端在上传时计算
+	EditedAt   *time.Time    // 最后编辑时间，没有编辑过为空
+	RecalledAt *time.Time    // 撤回时间，撤回后内容清空
 }
 
 func (Message) TableName() string {
 	return "message"
 }
+
+// MessageDeletion 用户在自己这一侧删除的消息，对方和其他群成员不受影响
+type MessageDeletion struct {
+	MessageID uint `gorm:"primarykey;autoIncrement:false"`
+	AccountID uint `gorm:"primarykey;autoIncrement:false;index"`
+	CreatedAt time.Time
+}
+
+func (MessageDeletion) TableName() string {
+	return "message_deletion"
+}
@@ -0,0 +1,18 @@
+package models
+
+// SOS 升级记录，每一次超时升级都会留下一条，供运营人员查看处理过程
+type SOSEscalationLog struct {
+	BaseModel
+	SOSID         uint    `gorm:"not null;index" json:"sos_id"`
+	Stage         int     `json:"stage"`                         // 升级阶段，从1开始
+	FromStatus    string  `gorm:"size:50" json:"from_status"`    // 升级前状态
+	ToStatus      string  `gorm:"size:50" json:"to_status"`      // 升级后状态
+	Radius        float64 `json:"radius"`                        // 本阶段匹配半径(米)
+	NotifiedCount int     `json:"notified_count"`                // 本阶段通知的志愿者数量
+	NotifiedIDs   string  `gorm:"type:text" json:"notified_ids"` // 通知的志愿者ID，逗号分隔
+	Note          string  `gorm:"size:255" json:"note"`
+}
+
+func (*SOSEscalationLog) TableName() string {
+	return "sos_escalation_log"
+}
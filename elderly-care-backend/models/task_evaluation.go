@@ -0,0 +1,16 @@
+package models
+
+// 任务评价，任务完成后发布人和接单人互评，每人每个任务只能评价一次
+type TaskEvaluation struct {
+	BaseModel
+	TaskID  uint   `gorm:"not null;uniqueIndex:unique_task_id_rater_id" json:"task_id"`
+	RaterID uint   `gorm:"not null;uniqueIndex:unique_task_id_rater_id" json:"rater_id"` // 评价人
+	RateeID uint   `gorm:"not null;index" json:"ratee_id"`                               // 被评价人
+	Score   int    `gorm:"not null" json:"score"`                                        // 1-5分
+	Comment string `gorm:"size:500" json:"comment"`
+	Tags    string `gorm:"size:255" json:"tags"` // 标签，逗号分隔
+}
+
+func (*TaskEvaluation) TableName() string {
+	return "task_evaluation"
+}
@@ -0,0 +1,15 @@
+package models
+
+// 任务状态变更记录
+type TaskStatusHistory struct {
+	BaseModel
+	TaskID     uint   `gorm:"not null;index" json:"task_id"`
+	FromStatus string `gorm:"size:50" json:"from_status"`
+	ToStatus   string `gorm:"size:50" json:"to_status"`
+	OperatorID uint   `json:"operator_id"` // 操作人，系统自动流转时为0
+	Reason     string `gorm:"size:255" json:"reason"`
+}
+
+func (*TaskStatusHistory) TableName() string {
+	return "task_status_history"
+}
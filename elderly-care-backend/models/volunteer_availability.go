@@ -0,0 +1,26 @@
+package models
+
+// 志愿者接单状态
+type VolunteerAvailability struct {
+	BaseModel
+	AccountID          uint `gorm:"uniqueIndex" json:"account_id"`
+	OnDuty             bool `gorm:"default:false" json:"on_duty"`          // 是否在岗，下岗后不参与匹配
+	MaxConcurrentTasks int  `gorm:"default:3" json:"max_concurrent_tasks"` // 同时进行的任务上限
+}
+
+func (*VolunteerAvailability) TableName() string {
+	return "volunteer_availability"
+}
+
+// 志愿者每周可服务时间段，没有配置时段视为全天可服务
+type AvailabilityWindow struct {
+	BaseModel
+	AccountID uint   `gorm:"not null;index" json:"account_id"`
+	Weekday   int    `json:"weekday"`                  // 0=周日 ... 6=周六
+	StartTime string `gorm:"size:5" json:"start_time"` // HH:MM
+	EndTime   string `gorm:"size:5" json:"end_time"`   // HH:MM，不跨天
+}
+
+func (*AvailabilityWindow) TableName() string {
+	return "availability_window"
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// 志愿者实名认证，证件照片存放在私有桶中，只保存对象名
+type VolunteerVerification struct {
+	BaseModel
+	AccountID     uint       `gorm:"uniqueIndex" json:"account_id"`
+	RealName      string     `gorm:"size:32" json:"real_name"`
+	IDFrontObject string     `gorm:"size:128" json:"-"` // 证件正面
+	IDBackObject  string     `gorm:"size:128" json:"-"` // 证件反面
+	Status        string     `gorm:"size:20;index" json:"status"`
+	Remark        string     `gorm:"size:255" json:"remark"` // 驳回或暂停原因
+	ReviewerID    *uint      `json:"reviewer_id"`
+	SubmittedAt   time.Time  `json:"submitted_at"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+}
+
+func (*VolunteerVerification) TableName() string {
+	return "volunteer_verification"
+}
@@ -1,7 +1,11 @@
 package routes
 
 import (
+	"elderly-care-backend/common/constants"
 	"elderly-care-backend/controllers"
+	"elderly-care-backend/global"
+	"elderly-care-backend/middlewares"
+	"elderly-care-backend/services"
 	"fmt" // 添加这行
 
 	"github.com/gin-gonic/gin"
@@ -9,7 +13,11 @@ import (
 
 func AccountRoute(e *gin.Engine) {
 	fmt.Println("   📍 注册账户路由组: /account")
-	controller := controllers.AccountController{}
+	// 冷静期已过的注销申请由后台任务执行
+	go services.NewAccountDeletionService(global.Db, global.RedisClient).Start()
+
+	controller := controllers.NewAccountController()
+	guardianController := controllers.NewGuardianController()
 	accountRoute := e.Group("/account")
 	{
 		accountRoute.POST("/register", controller.Register)
@@ -18,6 +26,25 @@ func AccountRoute(e *gin.Engine) {
 		accountRoute.POST("/login", controller.Login)
 		fmt.Println("     ✅ POST /account/login")
 
+		accountRoute.POST("/sms/code", controller.SendSmsCode)
+		fmt.Println("     ✅ POST /account/sms/code")
+
+		accountRoute.POST("/login/sms", controller.SmsLogin)
+		fmt.Println("     ✅ POST /account/login/sms")
+
+		accountRoute.POST("/phone/verify", controller.VerifyPhone)
+		fmt.Println("     ✅ POST /account/phone/verify")
+
+		accountRoute.POST("/password/reset/verify", controller.VerifyResetCode)
+		accountRoute.POST("/password/reset", controller.ResetPassword)
+		fmt.Println("     ✅ POST /account/password/reset")
+
+		accountRoute.POST("/refresh", controller.Refresh)
+		fmt.Println("     ✅ POST /account/refresh")
+
+		accountRoute.POST("/logout", controller.Logout)
+		fmt.Println("     ✅ POST /account/logout")
+
 		accountRoute.PUT("", controller.UpdateAccount)
 		fmt.Println("     ✅ PUT /account")
 
@@ -27,9 +54,33 @@ func AccountRoute(e *gin.Engine) {
 		accountRoute.GET("/checkPhone", controller.CheckPhoneIsExists)
 		fmt.Println("     ✅ GET /account/checkPhone")
 
+		accountRoute.GET("/export", controller.ExportData)
+		fmt.Println("     ✅ GET /account/export")
+
+		accountRoute.POST("/deletion", controller.RequestDeletion)
+		accountRoute.GET("/deletion", controller.GetDeletion)
+		accountRoute.DELETE("/deletion", controller.CancelDeletion)
+		fmt.Println("     ✅ /account/deletion")
+
 		accountRoute.GET("", controller.GetAccountInfo)
 		fmt.Println("     ✅ GET /account")
 		accountRoute.GET("/:accountID", controller.GetAccountInfoByAccountID)
+
+		// 监护人
+		accountRoute.POST("/guardians", middlewares.RequireRoles(constants.ROLE_ELDERLY), guardianController.AddGuardian)
+		accountRoute.GET("/guardians", guardianController.GetGuardians)
+		accountRoute.PUT("/guardians/:id", guardianController.UpdateGuardian)
+		accountRoute.DELETE("/guardians/:id", guardianController.RemoveGuardian)
+		accountRoute.PUT("/guardians/:id/confirm", guardianController.ConfirmGuardian)
+		accountRoute.PUT("/guardians/:id/reject", guardianController.RejectGuardian)
+		fmt.Println("     ✅ /account/guardians")
+
+		guardianOnly := middlewares.RequireRoles(constants.ROLE_GUARDIAN)
+		accountRoute.GET("/wards", guardianOnly, guardianController.GetWards)
+		accountRoute.GET("/wards/:elderId/sos", guardianOnly, guardianController.GetWardSOS)
+		accountRoute.GET("/wards/:elderId/location", guardianOnly, guardianController.GetWardLocation)
+		accountRoute.PUT("/wards/:elderId/password", guardianOnly, guardianController.ResetWardPassword)
+		fmt.Println("     ✅ /account/wards")
 	}
 	fmt.Println("   ✅ 账户路由注册完成")
 }
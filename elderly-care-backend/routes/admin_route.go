@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/controllers"
+	"elderly-care-backend/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoute(e *gin.Engine) {
+	adminController := controllers.NewAdminController()
+	// 运营人员可以查看，修改操作只允许管理员
+	adminRoute := e.Group("/admin", middlewares.RequireRoles(constants.ROLE_ADMIN, constants.ROLE_OPERATOR))
+	adminOnly := middlewares.RequireRoles(constants.ROLE_ADMIN)
+	{
+		adminRoute.GET("/accounts", adminController.ListAccounts)
+		adminRoute.PUT("/accounts/:accountId/suspend", adminOnly, adminController.SuspendAccount)
+		adminRoute.PUT("/accounts/:accountId/restore", adminOnly, adminController.RestoreAccount)
+		adminRoute.PUT("/accounts/:accountId/unlock", adminOnly, adminController.UnlockAccount)
+		adminRoute.GET("/login-attempts", adminController.ListLoginAttempts)
+
+		adminRoute.GET("/tasks", adminController.ListTasks)
+		adminRoute.GET("/sos", adminController.ListSOS)
+
+		adminRoute.GET("/verifications", adminController.ListVerifications)
+		adminRoute.GET("/verifications/:accountId/documents/:side", adminController.GetVerificationDocument)
+		adminRoute.PUT("/verifications/:accountId", adminOnly, adminController.ReviewVerification)
+	}
+}
@@ -5,13 +5,22 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func ChatRoute(e *gin.Engine) {
-	chatManager := controllers.NewConnectionManager()
-	go chatManager.Start()
+func ChatRoute(e *gin.Engine, chatManager *controllers.ChatManager) {
 	chatRoute := e.Group("/chat")
 	{
-		chatRoute.GET("", chatManager.HandleWebSocket)
 		chatRoute.GET("/record", chatManager.GetChatRecord)
 		chatRoute.GET("/contactList", chatManager.GetRecentlyChatList)
+		chatRoute.PUT("/read", chatManager.MarkRead)
+		chatRoute.POST("/messages/:messageId/recall", chatManager.RecallMessage)
+		chatRoute.PUT("/messages/:messageId", chatManager.EditMessage)
+		chatRoute.DELETE("/messages/:messageId", chatManager.DeleteMessage)
+		chatRoute.POST("/groups", chatManager.CreateGroup)
+		chatRoute.GET("/groups", chatManager.ListGroups)
+		chatRoute.GET("/groups/:groupId", chatManager.GetGroup)
+		chatRoute.POST("/groups/:groupId/invite", chatManager.InviteGroupMembers)
+		chatRoute.POST("/groups/:groupId/join", chatManager.JoinGroup)
+		chatRoute.POST("/groups/:groupId/leave", chatManager.LeaveGroup)
+		chatRoute.GET("/groups/:groupId/record", chatManager.GetGroupChatRecord)
+		chatRoute.PUT("/groups/:groupId/read", chatManager.MarkGroupRead)
 	}
 }
@@ -6,10 +6,11 @@ import (
 )
 
 func EvaluationRoute(e *gin.Engine) {
-	evaluationController := controllers.EvaluationController{}
+	evaluationController := controllers.NewEvaluationController()
 	evaluationRoute := e.Group("/evaluation")
 	{
-		evaluationRoute.PUT("/account", evaluationController.EvaluationAccount)
+		evaluationRoute.POST("/task", evaluationController.EvaluateTask)
+		evaluationRoute.GET("/task", evaluationController.GetTaskEvaluations)
 		evaluationRoute.GET("/account", evaluationController.GetAccountEvaluation)
 	}
 
@@ -2,15 +2,17 @@ package routes
 
 import (
 	"elderly-care-backend/controllers"
+	"elderly-care-backend/services"
 	"github.com/gin-gonic/gin"
 )
 
-func FileRoute(e *gin.Engine) {
+func FileRoute(e *gin.Engine, voiceService *services.ChatVoiceService) {
 
-	fileController := controllers.FileController{}
+	fileController := controllers.NewFileController(voiceService)
 	fileGroup := e.Group("/file")
 	{
 		fileGroup.POST("/upload", fileController.UploadFile)
+		fileGroup.POST("/voice", fileController.UploadVoice)
 	}
 
 }
@@ -10,7 +10,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func LocationRoute(e *gin.Engine, wsService *services.WebSocketService) {
+func LocationRoute(e *gin.Engine, wsService *services.RealtimeGateway) {
 	// 从配置创建高德地图服务
 	amapService := &services.AMapService{
 		APIKey:  config.Config.Map.AMap.APIKey, // 从配置读取
@@ -20,11 +20,15 @@ func LocationRoute(e *gin.Engine, wsService *services.WebSocketService) {
 	// 创建位置服务，传入全局Db实例
 	locationService := services.NewRealtimeLocationService(global.Db)
 
+	// 位置共享权限，WebSocket 推送在 wsService 中按同样的规则过滤
+	privacyService := services.NewLocationPrivacyService(global.Db)
+
 	// 创建控制器
 	controller := controllers.NewLocationController(
 		amapService,
 		locationService,
 		wsService,
+		privacyService,
 	)
 
 	locationRoute := e.Group("/location")
@@ -39,9 +43,4 @@ func LocationRoute(e *gin.Engine, wsService *services.WebSocketService) {
 		locationRoute.GET("/history", controller.GetLocationHistory)             // 新增
 		locationRoute.GET("/reverse-geocode", controller.ReverseGeocode)
 	}
-
-	// WebSocket路由
-	e.GET("/ws", func(c *gin.Context) {
-		wsService.HandleWebSocket(c.Writer, c.Request)
-	})
 }
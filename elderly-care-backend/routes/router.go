@@ -2,6 +2,8 @@
 package routes
 
 import (
+	"elderly-care-backend/controllers"
+	"elderly-care-backend/global"
 	"elderly-care-backend/middlewares"
 	"elderly-care-backend/services"
 
@@ -15,17 +17,30 @@ func SetUpRouter() *gin.Engine {
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	r.Use(middlewares.VerifyMiddleware())
 
-	// 初始化WebSocket服务
-	wsService := services.NewWebSocketService()
-	go wsService.Start() // 启动WebSocket服务
+	// 初始化实时网关，聊天、定位、任务和SOS推送共用一个已认证的 WebSocket 连接，
+	// 多实例部署时事件按 realtime.broker 配置在节点之间转发
+	cluster := services.NewRealtimeCluster(global.RedisClient)
+	wsService := services.NewRealtimeGateway(global.Db, global.RedisClient, cluster)
+	go wsService.Start()
+	r.GET("/ws", gin.WrapH(wsService))
+
+	// 语音消息的上传和发送共用，发送时使用上传时计算的时长
+	voiceService := services.NewChatVoiceService(global.RedisClient)
+
+	// 初始化聊天管理器，SOS等事件也通过它推送给指定用户
+	chatManager := controllers.NewConnectionManager(wsService,
+		services.NewChatService(global.Db, global.RedisClient), services.NewChatGroupService(global.Db), voiceService)
+	go chatManager.Start()
 
 	AccountRoute(r)
-	ChatRoute(r)
-	FileRoute(r)
+	ChatRoute(r, chatManager)
+	FileRoute(r, voiceService)
 	EvaluationRoute(r)
-	TaskRoute(r)                // 新增
-	SOSRoute(r)                 // 新增
-	LocationRoute(r, wsService) // 新增定位路由
+	VolunteerRoute(r)
+	AdminRoute(r)
+	TaskRoute(r, chatManager, wsService) // 新增
+	SOSRoute(r, chatManager, wsService)  // 新增
+	LocationRoute(r, wsService)          // 新增定位路由
 
 	return r
 }
@@ -1,18 +1,28 @@
 package routes
 
 import (
+	"elderly-care-backend/common/constants"
 	"elderly-care-backend/controllers"
+	"elderly-care-backend/global"
+	"elderly-care-backend/middlewares"
+	"elderly-care-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SOSRoute(e *gin.Engine) {
-	controller := controllers.NewSOSController()
+func SOSRoute(e *gin.Engine, chatManager *controllers.ChatManager, wsService *services.RealtimeGateway) {
+	// SOS超时升级任务，升级后的告警通过聊天连接推送
+	escalationService := services.NewSOSEscalationService(global.Db, &services.TaskMatchingService{})
+	escalationService.SetNotifier(chatManager)
+	go escalationService.Start()
+
+	controller := controllers.NewSOSController(escalationService, chatManager, wsService)
 	sosRoute := e.Group("/sos")
 	{
-		sosRoute.POST("/emergency", controller.TriggerEmergency)
-		sosRoute.POST("/:sosId/accept", controller.AcceptSOS)
+		sosRoute.POST("/emergency", middlewares.RequireRoles(constants.ROLE_ELDERLY, constants.ROLE_GUARDIAN), controller.TriggerEmergency)
+		sosRoute.POST("/:sosId/accept", middlewares.RequireRoles(constants.ROLE_VOLUNTEER), controller.AcceptSOS)
 		sosRoute.PUT("/:sosId/resolve", controller.ResolveSOS)
 		sosRoute.GET("/current", controller.GetCurrentSOS)
+		sosRoute.GET("/:sosId/escalations", controller.GetEscalations)
 	}
 }
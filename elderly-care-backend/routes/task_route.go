@@ -1,17 +1,35 @@
 package routes
 
 import (
+	"elderly-care-backend/common/constants"
 	"elderly-care-backend/controllers"
+	"elderly-care-backend/global"
+	"elderly-care-backend/middlewares"
+	"elderly-care-backend/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-func TaskRoute(e *gin.Engine) {
-	controller := controllers.NewTaskController()
+func TaskRoute(e *gin.Engine, chatManager *controllers.ChatManager, wsService *services.RealtimeGateway) {
+	// 过期任务扫描，过期后通知发布人
+	expiryService := services.NewTaskExpiryService(global.Db, services.NewTaskLifecycleService(global.Db), chatManager)
+	go expiryService.Start()
+
+	controller := controllers.NewTaskController(wsService)
+	// 老人或家属发布任务，志愿者接单和执行
+	requester := middlewares.RequireRoles(constants.ROLE_ELDERLY, constants.ROLE_GUARDIAN)
+	volunteer := middlewares.RequireRoles(constants.ROLE_VOLUNTEER)
 	taskRoute := e.Group("/tasks")
 	{
-		taskRoute.POST("/create", controller.CreateTask)
-		taskRoute.GET("/nearby", controller.GetNearbyTasks)
-		taskRoute.POST("/:taskId/accept", controller.AcceptTask)
+		taskRoute.POST("/create", requester, controller.CreateTask)
+		taskRoute.GET("/nearby", volunteer, controller.GetNearbyTasks)
+		taskRoute.POST("/:taskId/accept", volunteer, controller.AcceptTask)
+		taskRoute.POST("/:taskId/start", volunteer, controller.StartTask)
+		taskRoute.POST("/:taskId/complete", controller.CompleteTask)
+		taskRoute.POST("/:taskId/cancel", controller.CancelTask)
+		taskRoute.POST("/:taskId/abandon", volunteer, controller.AbandonTask)
+		taskRoute.POST("/:taskId/reassign", controller.ReassignTask)
+		taskRoute.GET("/:taskId/history", controller.GetTaskHistory)
+		taskRoute.PUT("/:taskId/deadline", controller.ExtendDeadline)
 	}
 }
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/controllers"
+	"elderly-care-backend/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+func VolunteerRoute(e *gin.Engine) {
+	volunteerController := controllers.NewVolunteerController()
+	volunteerRoute := e.Group("/volunteer", middlewares.RequireRoles(constants.ROLE_VOLUNTEER))
+	{
+		volunteerRoute.GET("/availability", volunteerController.GetAvailability)
+		volunteerRoute.PUT("/availability", volunteerController.UpdateAvailability)
+		volunteerRoute.PUT("/duty", volunteerController.SetDuty)
+		volunteerRoute.GET("/verification", volunteerController.GetVerification)
+		volunteerRoute.POST("/verification", volunteerController.SubmitVerification)
+	}
+}
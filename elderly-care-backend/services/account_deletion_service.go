@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/factories"
+	"elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDeletionNotExist         = errors.New(constants.DELETION_NOT_EXIST)
+	ErrDeletionAlreadyRequested = errors.New(constants.DELETION_ALREADY_REQUESTED)
+	ErrAccountHasOpenTasks      = errors.New(constants.ACCOUNT_HAS_OPEN_TASKS)
+)
+
+// AccountDeletionService 账号注销：申请后进入冷静期，到期后匿名化账号、删除位置轨迹，任务和SOS只保留去标识化的记录
+type AccountDeletionService struct {
+	db           *gorm.DB
+	tokenService *TokenService
+	groupService *ChatGroupService
+}
+
+func NewAccountDeletionService(db *gorm.DB, redisClient *redis.Client) *AccountDeletionService {
+	return &AccountDeletionService{
+		db:           db,
+		tokenService: NewTokenService(db, redisClient),
+		groupService: NewChatGroupService(db),
+	}
+}
+
+// GetDeletion 获取最近一次注销申请
+func (s *AccountDeletionService) GetDeletion(accountID uint) (*models.AccountDeletion, error) {
+	deletion := &models.AccountDeletion{}
+	err := s.db.Where("account_id = ?", accountID).Order("id DESC").Take(deletion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeletionNotExist
+	}
+	return deletion, err
+}
+
+// RequestDeletion 申请注销，有未结束的任务或SOS时不能申请
+func (s *AccountDeletionService) RequestDeletion(accountID uint, reason string) (*models.AccountDeletion, error) {
+	deletion := &models.AccountDeletion{
+		AccountID:   accountID,
+		Reason:      reason,
+		Status:      constants.DELETION_STATUS_PENDING,
+		ScheduledAt: time.Now().Add(constants.ACCOUNT_DELETION_GRACE_PERIOD),
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var pending int64
+		if err := tx.Model(&models.AccountDeletion{}).
+			Where("account_id = ? AND status = ?", accountID, constants.DELETION_STATUS_PENDING).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrDeletionAlreadyRequested
+		}
+		open, err := s.hasOpenWork(tx, accountID)
+		if err != nil {
+			return err
+		}
+		if open {
+			return ErrAccountHasOpenTasks
+		}
+		return tx.Create(deletion).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deletion, nil
+}
+
+// CancelDeletion 冷静期内撤销注销申请
+func (s *AccountDeletionService) CancelDeletion(accountID uint) error {
+	result := s.db.Model(&models.AccountDeletion{}).
+		Where("account_id = ? AND status = ?", accountID, constants.DELETION_STATUS_PENDING).
+		Updates(map[string]interface{}{
+			"status":       constants.DELETION_STATUS_CANCELLED,
+			"cancelled_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeletionNotExist
+	}
+	return nil
+}
+
+// 作为发布人或接单人有未结束的任务，或有未处理完的SOS
+func (s *AccountDeletionService) hasOpenWork(tx *gorm.DB, accountID uint) (bool, error) {
+	var tasks, sos int64
+	if err := tx.Model(&models.Task{}).
+		Where("(creator_id = ? OR assignee_id = ?) AND status IN ?", accountID, accountID, openTaskStatuses).
+		Count(&tasks).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&models.SOSRecord{}).
+		Where("user_id = ? AND status IN ?", accountID, openSOSStatuses).
+		Count(&sos).Error; err != nil {
+		return false, err
+	}
+	return tasks+sos > 0, nil
+}
+
+// Start 定时执行冷静期已过的注销申请
+func (s *AccountDeletionService) Start() {
+	ticker := time.NewTicker(constants.ACCOUNT_DELETION_SCAN_INTERVAL)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.scan()
+	}
+}
+
+func (s *AccountDeletionService) scan() {
+	// 多实例部署时只允许一个实例执行本轮扫描
+	mutex := global.LockManager.NewMutex(constants.LOCK_ACCOUNT_DELETION,
+		redsync.WithExpiry(constants.ACCOUNT_DELETION_SCAN_INTERVAL),
+		redsync.WithTries(1))
+	if err := mutex.Lock(); err != nil {
+		return
+	}
+	defer mutex.Unlock()
+
+	var deletions []models.AccountDeletion
+	if err := s.db.Where("status = ? AND scheduled_at <= ?", constants.DELETION_STATUS_PENDING, time.Now()).
+		Find(&deletions).Error; err != nil {
+		global.Logger.Error("scan account deletion error", zap.Error(err))
+		return
+	}
+	for _, deletion := range deletions {
+		if err := s.execute(&deletion); err != nil && !errors.Is(err, ErrAccountHasOpenTasks) {
+			global.Logger.Warn("delete account error", zap.Uint("account_id", deletion.AccountID), zap.Error(err))
+		}
+	}
+}
+
+// 执行注销：匿名化账号，删除位置轨迹、发出的聊天消息、监护关系、群成员身份和志愿者资料，任务和SOS去掉描述和精确地址
+func (s *AccountDeletionService) execute(deletion *models.AccountDeletion) error {
+	accountID := deletion.AccountID
+	var avatar string
+	var verification models.VolunteerVerification
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 以申请状态为条件更新，冷静期最后时刻撤销的申请不会被执行
+		result := tx.Model(&models.AccountDeletion{}).
+			Where("id = ? AND status = ?", deletion.ID, constants.DELETION_STATUS_PENDING).
+			Updates(map[string]interface{}{
+				"status":       constants.DELETION_STATUS_COMPLETED,
+				"completed_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrDeletionNotExist
+		}
+		// 冷静期内又接了任务或发起了SOS，等处理完后下一轮再执行
+		open, err := s.hasOpenWork(tx, accountID)
+		if err != nil {
+			return err
+		}
+		if open {
+			return ErrAccountHasOpenTasks
+		}
+
+		account := &models.Account{}
+		if err := tx.Select("id", "avatar").Take(account, accountID).Error; err != nil {
+			return err
+		}
+		avatar = account.Avatar
+		if err := tx.Where("account_id = ?", accountID).Take(&verification).Error; err != nil &&
+			!errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		deletedPhone := fmt.Sprintf("deleted_%d", accountID)
+		if err := tx.Model(&models.Account{}).Where("id = ?", accountID).Updates(map[string]interface{}{
+			"nickname":             constants.ACCOUNT_DELETED_NICKNAME,
+			"avatar":               "",
+			"sex":                  models.Man,
+			"phone":                deletedPhone,
+			"phone_verified":       false,
+			"password":             "",
+			"age":                  0,
+			"status":               constants.ACCOUNT_STATUS_DELETED,
+			"latitude":             0,
+			"longitude":            0,
+			"address":              "",
+			"last_location_update": nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		// 任务和SOS保留用于统计，坐标保留两位小数(约1公里)
+		deidentify := map[string]interface{}{
+			"description": "",
+			"address":     "",
+			"latitude":    gorm.Expr("ROUND(latitude, 2)"),
+			"longitude":   gorm.Expr("ROUND(longitude, 2)"),
+		}
+		if err := tx.Model(&models.Task{}).Where("creator_id = ?", accountID).Updates(deidentify).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SOSRecord{}).Where("user_id = ?", accountID).Updates(deidentify).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TaskEvaluation{}).Where("rater_id = ?", accountID).Update("comment", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.LoginAttempt{}).Where("account_id = ?", accountID).Updates(map[string]interface{}{
+			"phone":      deletedPhone,
+			"ip":         "",
+			"user_agent": "",
+		}).Error; err != nil {
+			return err
+		}
+
+		// 退出全部群聊，群主身份转让给其他成员
+		if err := s.groupService.LeaveAll(tx, accountID); err != nil {
+			return err
+		}
+
+		purges := []struct {
+			model interface{}
+			query *gorm.DB
+		}{
+			{&models.UserLocation{}, tx.Where("user_id = ?", accountID)},
+			{&models.Message{}, tx.Where("`from` = ?", accountID)},
+			{&models.MessageDeletion{}, tx.Where("account_id = ?", accountID)},
+			{&models.ContactList{}, tx.Where("account_id = ?", accountID)},
+			{&models.Guardian{}, tx.Where("elder_id = ? OR guardian_id = ?", accountID, accountID)},
+			{&models.VolunteerAvailability{}, tx.Where("account_id = ?", accountID)},
+			{&models.AvailabilityWindow{}, tx.Where("account_id = ?", accountID)},
+			{&models.VolunteerVerification{}, tx.Where("account_id = ?", accountID)},
+		}
+		for _, purge := range purges {
+			if err := purge.query.Delete(purge.model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = s.tokenService.RevokeAll(context.Background(), accountID); err != nil {
+		global.Logger.Warn("revoke deleted account sessions error", zap.Uint("account_id", accountID), zap.Error(err))
+	}
+	s.removeFiles(avatar, &verification)
+	global.Logger.Info("account deleted", zap.Uint("account_id", accountID))
+	return nil
+}
+
+// 删除头像和证件照，失败只记录日志
+func (s *AccountDeletionService) removeFiles(avatar string, verification *models.VolunteerVerification) {
+	client := factories.OssClientFactory.GetOssClient(factories.MINIO)
+	if client == nil {
+		return
+	}
+	objects := [][2]string{
+		{factories.VERIFICATION_BUCKET, verification.IDFrontObject},
+		{factories.VERIFICATION_BUCKET, verification.IDBackObject},
+	}
+	if avatar != "" {
+		objects = append(objects, [2]string{factories.ACCOUNT_AVATAR_BUCKET, path.Base(avatar)})
+	}
+	for _, object := range objects {
+		if object[1] == "" {
+			continue
+		}
+		if err := client.Remove(object[0], object[1]); err != nil {
+			global.Logger.Warn("remove account file error", zap.String("object", object[1]), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/factories"
+	"elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"elderly-care-backend/vo"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AccountExportService struct {
+	db *gorm.DB
+}
+
+func NewAccountExportService(db *gorm.DB) *AccountExportService {
+	return &AccountExportService{db: db}
+}
+
+// Export 汇总账号的全部个人数据，文件以链接形式给出，证件照等私有文件生成临时链接
+func (s *AccountExportService) Export(accountID uint) (*vo.AccountExportVO, error) {
+	export := &vo.AccountExportVO{ExportedAt: time.Now()}
+	if err := s.db.Take(&export.Account, accountID).Error; err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		dest  interface{}
+		query *gorm.DB
+	}{
+		{&export.Guardians, s.db.Where("elder_id = ? OR guardian_id = ?", accountID, accountID)},
+		{&export.CreatedTasks, s.db.Where("creator_id = ?", accountID)},
+		{&export.AssignedTasks, s.db.Where("assignee_id = ?", accountID)},
+		{&export.Evaluations, s.db.Where("rater_id = ? OR ratee_id = ?", accountID, accountID)},
+		{&export.SOSRecords, s.db.Where("user_id = ?", accountID)},
+		{&export.Locations, s.db.Where("user_id = ?", accountID)},
+		{&export.Messages, s.db.Where("`from` = ? OR `to` = ?", accountID, accountID)},
+		{&export.Contacts, s.db.Where("account_id = ?", accountID)},
+		{&export.LoginAttempts, s.db.Where("account_id = ?", accountID)},
+		{&export.Windows, s.db.Where("account_id = ?", accountID)},
+	}
+	for _, q := range queries {
+		if err := q.query.Order("id ASC").Find(q.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	availability := &models.VolunteerAvailability{}
+	if err := s.db.Where("account_id = ?", accountID).Take(availability).Error; err == nil {
+		export.Availability = availability
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	verification := &models.VolunteerVerification{}
+	if err := s.db.Where("account_id = ?", accountID).Take(verification).Error; err == nil {
+		export.Verification = verification
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	export.Media = s.collectMedia(export)
+	return export, nil
+}
+
+func (s *AccountExportService) collectMedia(export *vo.AccountExportVO) []vo.ExportMediaVO {
+	media := make([]vo.ExportMediaVO, 0)
+	if export.Account.Avatar != "" {
+		media = append(media, vo.ExportMediaVO{Source: "avatar", Url: export.Account.Avatar})
+	}
+	for _, message := range export.Messages {
+		if message.From == export.Account.ID && (message.Type == models.Image || message.Type == models.File) {
+			media = append(media, vo.ExportMediaVO{Source: "message", SourceID: message.ID, Url: message.Content})
+		}
+	}
+
+	verification := export.Verification
+	client := factories.OssClientFactory.GetOssClient(factories.MINIO)
+	if verification == nil || client == nil {
+		return media
+	}
+	expiresAt := time.Now().Add(constants.ACCOUNT_EXPORT_LINK_TTL)
+	for _, doc := range [][2]string{
+		{"id_front", verification.IDFrontObject},
+		{"id_back", verification.IDBackObject},
+	} {
+		source, objectName := doc[0], doc[1]
+		if objectName == "" {
+			continue
+		}
+		url, err := client.PresignedUrl(factories.VERIFICATION_BUCKET, objectName, constants.ACCOUNT_EXPORT_LINK_TTL)
+		if err != nil {
+			global.Logger.Warn("presign verification document error", zap.String("object", objectName), zap.Error(err))
+			continue
+		}
+		media = append(media, vo.ExportMediaVO{Source: source, SourceID: verification.ID, Url: url, ExpiresAt: &expiresAt})
+	}
+	return media
+}
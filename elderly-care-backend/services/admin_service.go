@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrCannotSuspendAdmin = errors.New(constants.NO_PERMISSION)
+
+// 未结束的任务和SOS状态，管理后台默认只看这些
+var (
+	openTaskStatuses = []string{
+		constants.TASK_STATUS_PENDING,
+		constants.TASK_STATUS_ASSIGNED,
+		constants.TASK_STATUS_IN_PROGRESS,
+	}
+	openSOSStatuses = []string{
+		constants.SOS_STATUS_PENDING,
+		constants.SOS_STATUS_MATCHING,
+		constants.SOS_STATUS_ACCEPTED,
+		constants.SOS_STATUS_IN_PROGRESS,
+		constants.SOS_STATUS_ESCALATED,
+	}
+)
+
+// AccountQuery 管理后台账号查询条件，字段为空时不过滤
+type AccountQuery struct {
+	Keyword string // 昵称或手机号
+	Role    string
+	Status  string
+}
+
+type AdminService struct {
+	db           *gorm.DB
+	tokenService *TokenService
+}
+
+func NewAdminService(db *gorm.DB, tokenService *TokenService) *AdminService {
+	return &AdminService{db: db, tokenService: tokenService}
+}
+
+// SearchAccounts 分页查询账号
+func (s *AdminService) SearchAccounts(query AccountQuery, page, pageSize int) ([]models.Account, int64, error) {
+	db := s.db.Model(&models.Account{})
+	if query.Keyword != "" {
+		like := "%" + query.Keyword + "%"
+		db = db.Where("nickname LIKE ? OR phone LIKE ?", like, like)
+	}
+	if query.Role != "" {
+		db = db.Where("role = ?", query.Role)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var accounts []models.Account
+	err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&accounts).Error
+	return accounts, total, err
+}
+
+// SuspendAccount 暂停账号并吊销其所有会话，志愿者同时下岗；管理员账号不能被暂停
+func (s *AdminService) SuspendAccount(ctx context.Context, accountID uint, reason string) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		account := &models.Account{}
+		if err := tx.Select("id", "role").Take(account, accountID).Error; err != nil {
+			return err
+		}
+		if account.Role == constants.ROLE_ADMIN {
+			return ErrCannotSuspendAdmin
+		}
+
+		if err := tx.Model(account).Updates(map[string]interface{}{
+			"status":         constants.ACCOUNT_STATUS_SUSPENDED,
+			"suspend_reason": reason,
+			"suspended_at":   time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.VolunteerAvailability{}).
+			Where("account_id = ?", accountID).
+			Update("on_duty", false).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.tokenService.RevokeAll(ctx, accountID)
+}
+
+// RestoreAccount 恢复被暂停的账号，已注销或正常的账号视为不存在
+func (s *AdminService) RestoreAccount(accountID uint) error {
+	result := s.db.Model(&models.Account{}).Where("id = ? AND status = ?", accountID, constants.ACCOUNT_STATUS_SUSPENDED).
+		Updates(map[string]interface{}{
+			"status":         constants.ACCOUNT_STATUS_ACTIVE,
+			"suspend_reason": "",
+			"suspended_at":   nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListTasks 分页查询任务，status 为空时查询所有未结束的任务
+func (s *AdminService) ListTasks(status string, page, pageSize int) ([]models.Task, int64, error) {
+	db := s.db.Model(&models.Task{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	} else {
+		db = db.Where("status IN ?", openTaskStatuses)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var tasks []models.Task
+	err := db.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&tasks).Error
+	return tasks, total, err
+}
+
+// ListSOS 分页查询SOS记录，status 为空时查询所有未解决的记录
+func (s *AdminService) ListSOS(status string, page, pageSize int) ([]models.SOSRecord, int64, error) {
+	db := s.db.Model(&models.SOSRecord{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	} else {
+		db = db.Where("status IN ?", openSOSStatuses)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var records []models.SOSRecord
+	err := db.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records).Error
+	return records, total, err
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/models"
+	"elderly-care-backend/vo"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ErrChatGroupNotExist   = FrameError(constants.CHAT_GROUP_NOT_EXIST)
+	ErrChatGroupNotMember  = FrameError(constants.CHAT_GROUP_NOT_MEMBER)
+	ErrChatGroupNotOwner   = FrameError(constants.CHAT_GROUP_NOT_OWNER)
+	ErrChatGroupNotInvited = FrameError(constants.CHAT_GROUP_NOT_INVITED)
+	ErrChatGroupFull       = FrameError(constants.CHAT_GROUP_FULL)
+)
+
+// ChatGroupService 群聊：群主邀请，被邀请人加入后才能收发消息；群主退出时转让给最早加入的成员
+type ChatGroupService struct {
+	db *gorm.DB
+}
+
+func NewChatGroupService(db *gorm.DB) *ChatGroupService {
+	return &ChatGroupService{db: db}
+}
+
+// CreateGroup 创建群聊，创建人为群主，memberIDs 中的用户收到邀请，返回实际被邀请的用户
+func (s *ChatGroupService) CreateGroup(ownerID uint, name string, memberIDs []uint) (*models.ChatGroup, []uint, error) {
+	group := &models.ChatGroup{Name: name, OwnerID: ownerID}
+	var invited []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+		if err := s.addJoined(tx, group, ownerID, constants.CHAT_GROUP_ROLE_OWNER); err != nil {
+			return err
+		}
+		var err error
+		invited, err = s.invite(tx, group.ID, ownerID, memberIDs)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return group, invited, nil
+}
+
+// Invite 群主邀请成员，已在群里或已被邀请的用户忽略，返回实际被邀请的用户
+func (s *ChatGroupService) Invite(operatorID, groupID uint, accountIDs []uint) ([]uint, error) {
+	var invited []uint
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		member, err := s.joinedMember(tx, groupID, operatorID)
+		if err != nil {
+			return err
+		}
+		if member.Role != constants.CHAT_GROUP_ROLE_OWNER {
+			return ErrChatGroupNotOwner
+		}
+		invited, err = s.invite(tx, groupID, operatorID, accountIDs)
+		return err
+	})
+	return invited, err
+}
+
+// 只邀请存在且没有注销的账号
+func (s *ChatGroupService) invite(tx *gorm.DB, groupID, inviterID uint, accountIDs []uint) ([]uint, error) {
+	candidates := make([]uint, 0, len(accountIDs))
+	seen := map[uint]bool{inviterID: true}
+	for _, accountID := range accountIDs {
+		if !seen[accountID] {
+			seen[accountID] = true
+			candidates = append(candidates, accountID)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	var valid, existing []uint
+	if err := tx.Model(&models.Account{}).
+		Where("id IN ? AND status <> ?", candidates, constants.ACCOUNT_STATUS_DELETED).
+		Pluck("id", &valid).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.ChatGroupMember{}).Where("group_id = ?", groupID).
+		Pluck("account_id", &existing).Error; err != nil {
+		return nil, err
+	}
+	inGroup := make(map[uint]bool, len(existing))
+	for _, accountID := range existing {
+		inGroup[accountID] = true
+	}
+
+	members := make([]models.ChatGroupMember, 0, len(valid))
+	invited := make([]uint, 0, len(valid))
+	for _, accountID := range valid {
+		if inGroup[accountID] {
+			continue
+		}
+		members = append(members, models.ChatGroupMember{
+			GroupID:   groupID,
+			AccountID: accountID,
+			Role:      constants.CHAT_GROUP_ROLE_MEMBER,
+			Status:    constants.CHAT_GROUP_MEMBER_INVITED,
+			InvitedBy: inviterID,
+		})
+		invited = append(invited, accountID)
+	}
+	if len(members) == 0 {
+		return invited, nil
+	}
+	if len(existing)+len(members) > constants.CHAT_GROUP_MAX_MEMBERS {
+		return nil, ErrChatGroupFull
+	}
+	return invited, tx.Create(&members).Error
+}
+
+// 直接以加入状态添加成员，已被邀请的改为加入；从当前最后一条消息开始计算未读
+func (s *ChatGroupService) addJoined(tx *gorm.DB, group *models.ChatGroup, accountID uint, role string) error {
+	now := time.Now()
+	member := &models.ChatGroupMember{}
+	err := tx.Where("group_id = ? AND account_id = ?", group.ID, accountID).Take(member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Create(&models.ChatGroupMember{
+			GroupID:            group.ID,
+			AccountID:          accountID,
+			Role:               role,
+			Status:             constants.CHAT_GROUP_MEMBER_JOINED,
+			JoinedAt:           &now,
+			DeliveredMessageID: group.LastMessageID,
+			ReadMessageID:      group.LastMessageID,
+		}).Error
+	}
+	if err != nil || member.Status == constants.CHAT_GROUP_MEMBER_JOINED {
+		return err
+	}
+	return tx.Model(member).Updates(map[string]interface{}{
+		"status":               constants.CHAT_GROUP_MEMBER_JOINED,
+		"joined_at":            now,
+		"delivered_message_id": group.LastMessageID,
+		"read_message_id":      group.LastMessageID,
+	}).Error
+}
+
+// Join 接受邀请加入群聊，已加入时直接返回
+func (s *ChatGroupService) Join(accountID, groupID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		group := &models.ChatGroup{}
+		err := tx.Take(group, groupID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrChatGroupNotExist
+		}
+		if err != nil {
+			return err
+		}
+		member := &models.ChatGroupMember{}
+		err = tx.Where("group_id = ? AND account_id = ?", groupID, accountID).Take(member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrChatGroupNotInvited
+		}
+		if err != nil {
+			return err
+		}
+		return s.addJoined(tx, group, accountID, member.Role)
+	})
+}
+
+// Leave 退出群聊，被邀请人退出即拒绝邀请
+func (s *ChatGroupService) Leave(accountID, groupID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		member := &models.ChatGroupMember{}
+		err := tx.Where("group_id = ? AND account_id = ?", groupID, accountID).Take(member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrChatGroupNotMember
+		}
+		if err != nil {
+			return err
+		}
+		return s.leave(tx, member)
+	})
+}
+
+// LeaveAll 退出用户所在的全部群聊，注销账号时使用
+func (s *ChatGroupService) LeaveAll(tx *gorm.DB, accountID uint) error {
+	var members []models.ChatGroupMember
+	if err := tx.Where("account_id = ?", accountID).Find(&members).Error; err != nil {
+		return err
+	}
+	for i := range members {
+		if err := s.leave(tx, &members[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 群主退出时转让给最早加入的成员，没有其他成员时解散
+func (s *ChatGroupService) leave(tx *gorm.DB, member *models.ChatGroupMember) error {
+	if err := tx.Delete(member).Error; err != nil {
+		return err
+	}
+	if member.Role != constants.CHAT_GROUP_ROLE_OWNER {
+		return nil
+	}
+
+	next := &models.ChatGroupMember{}
+	err := tx.Where("group_id = ? AND status = ?", member.GroupID, constants.CHAT_GROUP_MEMBER_JOINED).
+		Order("joined_at ASC, id ASC").Take(next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err = tx.Where("group_id = ?", member.GroupID).Delete(&models.ChatGroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ChatGroup{}, member.GroupID).Error
+	}
+	if err != nil {
+		return err
+	}
+	if err = tx.Model(next).Update("role", constants.CHAT_GROUP_ROLE_OWNER).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.ChatGroup{}).Where("id = ?", member.GroupID).Update("owner_id", next.AccountID).Error
+}
+
+// ListGroups 用户加入的和被邀请的群聊，按最后一条消息倒序
+func (s *ChatGroupService) ListGroups(accountID uint) ([]vo.ChatGroupVO, error) {
+	groups := make([]vo.ChatGroupVO, 0)
+	err := s.db.Table("chat_group_member AS m").
+		Select("g.id, g.name, g.owner_id, g.task_id, m.role, m.status, m.unread_count, g.last_message_id, "+
+			"message.from AS last_message_from, message.type AS last_message_type, "+
+			"message.content AS last_message_preview, message.recalled_at IS NOT NULL AS last_message_recalled, "+
+			"message.time AS last_chat_time").
+		Joins("JOIN chat_group AS g ON g.id = m.group_id").
+		Joins("LEFT JOIN message ON message.id = g.last_message_id").
+		Where("m.account_id = ?", accountID).
+		Order("g.last_message_id DESC, g.id DESC").
+		Scan(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		groups[i].LastMessagePreview = MessagePreview(groups[i].LastMessageType, groups[i].LastMessagePreview, groups[i].LastMessageRecalled)
+	}
+	return groups, nil
+}
+
+// GetGroup 群详情和成员，被邀请人也可以查看
+func (s *ChatGroupService) GetGroup(accountID, groupID uint) (*vo.ChatGroupDetailVO, error) {
+	detail := &vo.ChatGroupDetailVO{}
+	err := s.db.Take(&detail.ChatGroup, groupID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChatGroupNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	detail.Members = make([]vo.ChatGroupMemberVO, 0)
+	if err = s.db.Table("chat_group_member AS m").
+		Select("m.account_id, account.nickname, account.avatar, m.role, m.status, m.invited_by, m.joined_at").
+		Joins("JOIN account ON account.id = m.account_id").
+		Where("m.group_id = ?", groupID).
+		Order("m.id ASC").
+		Scan(&detail.Members).Error; err != nil {
+		return nil, err
+	}
+	for _, member := range detail.Members {
+		if member.AccountID == accountID {
+			return detail, nil
+		}
+	}
+	return nil, ErrChatGroupNotMember
+}
+
+// JoinedMemberIDs 已加入的成员，accountID 不在其中时返回 ErrChatGroupNotMember
+func (s *ChatGroupService) JoinedMemberIDs(accountID, groupID uint) ([]uint, error) {
+	var memberIDs []uint
+	if err := s.db.Model(&models.ChatGroupMember{}).
+		Where("group_id = ? AND status = ?", groupID, constants.CHAT_GROUP_MEMBER_JOINED).
+		Pluck("account_id", &memberIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, memberID := range memberIDs {
+		if memberID == accountID {
+			return memberIDs, nil
+		}
+	}
+	return nil, ErrChatGroupNotMember
+}
+
+func (s *ChatGroupService) joinedMember(tx *gorm.DB, groupID, accountID uint) (*models.ChatGroupMember, error) {
+	member := &models.ChatGroupMember{}
+	err := tx.Where("group_id = ? AND account_id = ? AND status = ?", groupID, accountID, constants.CHAT_GROUP_MEMBER_JOINED).
+		Take(member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChatGroupNotMember
+	}
+	return member, err
+}
+
+// Record 群聊记录，messageID 不为 0 时返回该消息之前的记录，用于上滑加载
+func (s *ChatGroupService) Record(accountID, groupID, messageID uint, size int) ([]models.Message, error) {
+	if _, err := s.joinedMember(s.db, groupID, accountID); err != nil {
+		return nil, err
+	}
+	query := notDeletedBy(s.db, accountID).Where("group_id = ?", groupID)
+	if messageID != 0 {
+		query = query.Where("id < ?", messageID)
+	}
+	msgs := make([]models.Message, 0)
+	err := query.Order("time desc").Limit(size).Find(&msgs).Error
+	return msgs, err
+}
+
+// EnsureTaskGroup 任务被接单后创建任务群，发布人、接单人和发布人已确认的监护人直接加入；
+// 改派后新的接单人加入，原接单人 previousAssigneeID 移出群聊（同时是监护人的除外）
+func (s *ChatGroupService) EnsureTaskGroup(task *models.Task, previousAssigneeID *uint) error {
+	if task.AssigneeID == nil {
+		return nil
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		group := &models.ChatGroup{}
+		err := tx.Where("task_id = ?", task.ID).Take(group).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			name := []rune(constants.CHAT_GROUP_TASK_PREFIX + task.Title)
+			group = &models.ChatGroup{
+				Name:    string(name[:min(len(name), 64)]),
+				OwnerID: task.CreatorID,
+				TaskID:  &task.ID,
+			}
+			err = tx.Create(group).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		var guardianIDs []uint
+		if err = tx.Model(&models.Guardian{}).
+			Where("elder_id = ? AND status = ?", task.CreatorID, constants.GUARDIAN_STATUS_VERIFIED).
+			Pluck("guardian_id", &guardianIDs).Error; err != nil {
+			return err
+		}
+		// 发布人退群后群主已转让，再加入时只是普通成员
+		creatorRole := constants.CHAT_GROUP_ROLE_MEMBER
+		if group.OwnerID == task.CreatorID {
+			creatorRole = constants.CHAT_GROUP_ROLE_OWNER
+		}
+		if err = s.addJoined(tx, group, task.CreatorID, creatorRole); err != nil {
+			return err
+		}
+		for _, accountID := range append(guardianIDs, *task.AssigneeID) {
+			if err = s.addJoined(tx, group, accountID, constants.CHAT_GROUP_ROLE_MEMBER); err != nil {
+				return err
+			}
+		}
+
+		if previousAssigneeID == nil || *previousAssigneeID == *task.AssigneeID ||
+			*previousAssigneeID == task.CreatorID || slices.Contains(guardianIDs, *previousAssigneeID) {
+			return nil
+		}
+		return s.removeMember(tx, group.ID, *previousAssigneeID)
+	})
+}
+
+// LeaveTaskGroup 志愿者放弃或发布人取消任务后，原接单人移出任务群（同时是发布人或监护人的除外）
+func (s *ChatGroupService) LeaveTaskGroup(task *models.Task, assigneeID uint) error {
+	if assigneeID == task.CreatorID {
+		return nil
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		group := &models.ChatGroup{}
+		err := tx.Where("task_id = ?", task.ID).Take(group).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var guardians int64
+		if err = tx.Model(&models.Guardian{}).
+			Where("elder_id = ? AND guardian_id = ? AND status = ?", task.CreatorID, assigneeID, constants.GUARDIAN_STATUS_VERIFIED).
+			Count(&guardians).Error; err != nil {
+			return err
+		}
+		if guardians > 0 {
+			return nil
+		}
+		return s.removeMember(tx, group.ID, assigneeID)
+	})
+}
+
+// 按退群处理，不在群里时直接返回
+func (s *ChatGroupService) removeMember(tx *gorm.DB, groupID, accountID uint) error {
+	member := &models.ChatGroupMember{}
+	err := tx.Where("group_id = ? AND account_id = ?", groupID, accountID).Take(member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.leave(tx, member)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/models"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func joinedIDs(t *testing.T, db *gorm.DB, groupID uint) map[uint]string {
+	t.Helper()
+	var members []models.ChatGroupMember
+	if err := db.Where("group_id = ? AND status = ?", groupID, constants.CHAT_GROUP_MEMBER_JOINED).Find(&members).Error; err != nil {
+		t.Fatalf("load members: %v", err)
+	}
+	roles := make(map[uint]string, len(members))
+	for _, member := range members {
+		roles[member.AccountID] = member.Role
+	}
+	return roles
+}
+
+func TestEnsureTaskGroupReassign(t *testing.T) {
+	db := openTestDB(t, &models.ChatGroup{}, &models.ChatGroupMember{}, &models.Guardian{})
+	service := NewChatGroupService(db)
+	now := time.Now()
+	// 1号老人，2号是他的监护人，3号和4号是志愿者
+	if err := db.Create(&models.Guardian{ElderID: 1, GuardianID: 2, Status: constants.GUARDIAN_STATUS_VERIFIED, VerifiedAt: &now}).Error; err != nil {
+		t.Fatalf("create guardian: %v", err)
+	}
+	first, second := uint(3), uint(4)
+	task := &models.Task{BaseModel: models.BaseModel{ID: 12}, CreatorID: 1, Title: "买菜", AssigneeID: &first}
+	if err := service.EnsureTaskGroup(task, nil); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+	group := &models.ChatGroup{}
+	if err := db.Where("task_id = ?", task.ID).Take(group).Error; err != nil {
+		t.Fatalf("load group: %v", err)
+	}
+	members := joinedIDs(t, db, group.ID)
+	if len(members) != 3 || members[1] != constants.CHAT_GROUP_ROLE_OWNER || members[3] != constants.CHAT_GROUP_ROLE_MEMBER {
+		t.Fatalf("members after accept = %v", members)
+	}
+
+	// 改派后原接单人移出，新接单人加入
+	task.AssigneeID = &second
+	if err := service.EnsureTaskGroup(task, &first); err != nil {
+		t.Fatalf("ensure group after reassign: %v", err)
+	}
+	members = joinedIDs(t, db, group.ID)
+	if _, ok := members[first]; ok || len(members) != 3 || members[second] != constants.CHAT_GROUP_ROLE_MEMBER {
+		t.Fatalf("members after reassign = %v", members)
+	}
+
+	// 原接单人不能再自己加回群里
+	if err := service.Join(first, group.ID); !errors.Is(err, ErrChatGroupNotInvited) {
+		t.Errorf("rejoin = %v, want %v", err, ErrChatGroupNotInvited)
+	}
+}
+
+func TestLeaveTaskGroupAfterAbandon(t *testing.T) {
+	db := openTestDB(t, &models.ChatGroup{}, &models.ChatGroupMember{}, &models.Guardian{})
+	service := NewChatGroupService(db)
+	now := time.Now()
+	// 2号是老人的监护人，同时也是志愿者
+	if err := db.Create(&models.Guardian{ElderID: 1, GuardianID: 2, Status: constants.GUARDIAN_STATUS_VERIFIED, VerifiedAt: &now}).Error; err != nil {
+		t.Fatalf("create guardian: %v", err)
+	}
+	volunteer, guardian := uint(3), uint(2)
+	task := &models.Task{BaseModel: models.BaseModel{ID: 13}, CreatorID: 1, Title: "陪诊", AssigneeID: &volunteer}
+	if err := service.EnsureTaskGroup(task, nil); err != nil {
+		t.Fatalf("ensure group: %v", err)
+	}
+	group := &models.ChatGroup{}
+	if err := db.Where("task_id = ?", task.ID).Take(group).Error; err != nil {
+		t.Fatalf("load group: %v", err)
+	}
+
+	// 志愿者放弃后移出任务群，之后重新接单的是监护人
+	task.AssigneeID = nil
+	if err := service.LeaveTaskGroup(task, volunteer); err != nil {
+		t.Fatalf("leave after abandon: %v", err)
+	}
+	members := joinedIDs(t, db, group.ID)
+	if _, ok := members[volunteer]; ok || len(members) != 2 {
+		t.Fatalf("members after abandon = %v", members)
+	}
+	task.AssigneeID = &guardian
+	if err := service.EnsureTaskGroup(task, nil); err != nil {
+		t.Fatalf("ensure group after accept: %v", err)
+	}
+
+	// 取消后接单人如果是监护人，仍然留在群里
+	if err := service.LeaveTaskGroup(task, guardian); err != nil {
+		t.Fatalf("leave after cancel: %v", err)
+	}
+	members = joinedIDs(t, db, group.ID)
+	if _, ok := members[guardian]; !ok || len(members) != 2 {
+		t.Fatalf("members after cancel = %v", members)
+	}
+
+	// 没有任务群时直接返回
+	other := &models.Task{BaseModel: models.BaseModel{ID: 14}, CreatorID: 1}
+	if err := service.LeaveTaskGroup(other, volunteer); err != nil {
+		t.Errorf("leave without group: %v", err)
+	}
+}
+
+func TestJoinDissolvedGroup(t *testing.T) {
+	db := openTestDB(t, &models.ChatGroup{}, &models.ChatGroupMember{})
+	service := NewChatGroupService(db)
+	// 邀请还在，群已经解散
+	if err := db.Create(&models.ChatGroupMember{GroupID: 404, AccountID: 5, Role: constants.CHAT_GROUP_ROLE_MEMBER,
+		Status: constants.CHAT_GROUP_MEMBER_INVITED}).Error; err != nil {
+		t.Fatalf("create invitation: %v", err)
+	}
+	if err := service.Join(5, 404); !errors.Is(err, ErrChatGroupNotExist) {
+		t.Errorf("join = %v, want %v", err, ErrChatGroupNotExist)
+	}
+}
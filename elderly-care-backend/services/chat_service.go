@@ -0,0 +1,467 @@
+package services
+
+import (
+	"context"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/config"
+	"elderly-care-backend/models"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	ErrMessageStatusInvalid  = FrameError(constants.PARAM_ERROR)
+	ErrMessageContentInvalid = FrameError(constants.PARAM_ERROR)
+	ErrMessageNotExist       = FrameError(constants.MESSAGE_NOT_EXIST)
+	ErrMessageNotSender      = FrameError(constants.MESSAGE_NOT_SENDER)
+	ErrMessageRecalled       = FrameError(constants.MESSAGE_RECALLED)
+	ErrMessageRecallExpired  = FrameError(constants.MESSAGE_RECALL_EXPIRED)
+	ErrMessageNotEditable    = FrameError(constants.MESSAGE_NOT_EDITABLE)
+)
+
+// ChatService 聊天消息落库、送达/已读回执和未读数。
+// 消息经 kafka 异步落库，回执可能先于消息到达，因此确认进度记录在接收方的会话上，落库时据此确定消息状态
+type ChatService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+func NewChatService(db *gorm.DB, redisClient *redis.Client) *ChatService {
+	return &ChatService{db: db, redisClient: redisClient}
+}
+
+// SaveMessage 保存消息并更新双方的会话，kafka 重复投递的消息直接忽略
+func (s *ChatService) SaveMessage(msg *models.Message) error {
+	if msg.GroupID != 0 {
+		return s.saveGroupMessage(msg)
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		receiver := &models.ContactList{}
+		err := tx.Where("account_id = ? AND contact_id = ?", msg.To, msg.From).Take(receiver).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		switch {
+		case msg.ID <= receiver.ReadMessageID:
+			msg.Status = models.Read
+		case msg.ID <= receiver.DeliveredMessageID:
+			msg.Status = models.Delivered
+		default:
+			msg.Status = models.Sent
+		}
+		if err = tx.Create(msg).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return nil
+			}
+			return err
+		}
+
+		if err = s.touchContact(tx, msg.From, msg.To, msg, false); err != nil {
+			return err
+		}
+		if msg.To == msg.From {
+			return nil
+		}
+		return s.touchContact(tx, msg.To, msg.From, msg, msg.Status < models.Read)
+	})
+}
+
+// 保存群消息，更新群的最后一条消息和其他成员的未读数
+func (s *ChatService) saveGroupMessage(msg *models.Message) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		msg.Status = models.Sent
+		if err := tx.Create(msg).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return nil
+			}
+			return err
+		}
+		if err := tx.Model(&models.ChatGroup{}).Where("id = ?", msg.GroupID).
+			Update("last_message_id", gorm.Expr("GREATEST(last_message_id, ?)", msg.ID)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ChatGroupMember{}).
+			Where("group_id = ? AND account_id <> ? AND status = ? AND read_message_id < ?",
+				msg.GroupID, msg.From, constants.CHAT_GROUP_MEMBER_JOINED, msg.ID).
+			Update("unread_count", gorm.Expr("unread_count + 1")).Error
+	})
+}
+
+// 更新会话的最后一条消息，kafka 多分区下消息可能乱序到达，只保留较新的
+func (s *ChatService) touchContact(tx *gorm.DB, accountID, contactID uint, msg *models.Message, unread bool) error {
+	contact := &models.ContactList{
+		AccountID:     accountID,
+		ContactID:     contactID,
+		LastChatTime:  msg.Time,
+		LastMessageID: msg.ID,
+	}
+	updates := map[string]interface{}{
+		"last_chat_time":  gorm.Expr("GREATEST(last_chat_time, ?)", msg.Time),
+		"last_message_id": gorm.Expr("GREATEST(last_message_id, ?)", msg.ID),
+	}
+	if unread {
+		contact.UnreadCount = 1
+		updates["unread_count"] = gorm.Expr("unread_count + 1")
+	}
+	return upsertContact(tx, contact, updates)
+}
+
+// 更新会话，不存在时创建。MySQL 的影响行数不含值没有变化的行，此时创建会因唯一索引冲突失败，忽略即可
+func upsertContact(tx *gorm.DB, contact *models.ContactList, updates map[string]interface{}) error {
+	result := tx.Model(&models.ContactList{}).
+		Where("account_id = ? AND contact_id = ?", contact.AccountID, contact.ContactID).
+		Updates(updates)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	if err := tx.Create(contact).Error; err != nil && !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return err
+	}
+	return nil
+}
+
+// Acknowledge accountID 确认 contactID 发来的消息中 ID 不超过 messageID 的都已送达或已读，
+// messageID 为 0 时确认到最新一条；已读会同时重新计算未读数
+func (s *ChatService) Acknowledge(accountID, contactID, messageID uint, status models.MessageStatus) (*vo.ChatReceiptVO, error) {
+	if status != models.Delivered && status != models.Read {
+		return nil, ErrMessageStatusInvalid
+	}
+	messageID, err := s.ackMessageID(messageID, s.db.Where("`from` = ? AND `to` = ?", contactID, accountID))
+	if err != nil {
+		return nil, err
+	}
+	receipt := &vo.ChatReceiptVO{From: contactID, To: accountID, MessageID: messageID, Status: status}
+	if messageID == 0 {
+		return receipt, nil
+	}
+	// 对方没有给自己发过消息时不确认，避免给任意用户建立联系人、发出回执
+	related, err := s.hasConversation(accountID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if !related {
+		receipt.MessageID = 0
+		return receipt, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		contact := &models.ContactList{
+			AccountID:          accountID,
+			ContactID:          contactID,
+			LastChatTime:       time.Now(),
+			DeliveredMessageID: messageID,
+		}
+		updates := map[string]interface{}{
+			"delivered_message_id": gorm.Expr("GREATEST(delivered_message_id, ?)", messageID),
+		}
+		if status == models.Read {
+			contact.ReadMessageID = messageID
+			updates["read_message_id"] = gorm.Expr("GREATEST(read_message_id, ?)", messageID)
+		}
+		if err := upsertContact(tx, contact, updates); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Message{}).
+			Where("`from` = ? AND `to` = ? AND id <= ? AND status < ?", contactID, accountID, messageID, status).
+			Update("status", status).Error; err != nil {
+			return err
+		}
+		if status != models.Read {
+			return nil
+		}
+		var unread int64
+		if err := tx.Model(&models.Message{}).
+			Where("`from` = ? AND `to` = ? AND status < ?", contactID, accountID, models.Read).
+			Count(&unread).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ContactList{}).
+			Where("account_id = ? AND contact_id = ?", accountID, contactID).
+			Update("unread_count", unread).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// accountID 这一侧已有和 contactID 的会话，或者已经收到过 contactID 发来的消息(可能还没建立会话)
+func (s *ChatService) hasConversation(accountID, contactID uint) (bool, error) {
+	for _, query := range []*gorm.DB{
+		s.db.Model(&models.ContactList{}).Where("account_id = ? AND contact_id = ?", accountID, contactID),
+		s.db.Model(&models.Message{}).Where("`from` = ? AND `to` = ?", contactID, accountID),
+	} {
+		var count int64
+		if err := query.Limit(1).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// 确认到的消息ID：为 0 时取 scope 范围内最新一条；
+// 不能确认还没发出的消息ID，否则之后收到的消息会直接变成已读
+func (s *ChatService) ackMessageID(messageID uint, scope *gorm.DB) (uint, error) {
+	if messageID == 0 {
+		err := scope.Model(&models.Message{}).Select("COALESCE(MAX(id), 0)").Scan(&messageID).Error
+		return messageID, err
+	}
+	lastID, err := s.redisClient.Get(context.Background(), constants.CHAT_ID_COUNT).Uint64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+	return min(messageID, uint(lastID)), nil
+}
+
+// AcknowledgeGroup 群成员确认群内 ID 不超过 messageID 的消息都已送达或已读，messageID 为 0 时确认到最新一条
+func (s *ChatService) AcknowledgeGroup(accountID, groupID, messageID uint, status models.MessageStatus) (*vo.ChatReceiptVO, error) {
+	if status != models.Delivered && status != models.Read {
+		return nil, ErrMessageStatusInvalid
+	}
+	member := &models.ChatGroupMember{}
+	err := s.db.Where("group_id = ? AND account_id = ? AND status = ?", groupID, accountID, constants.CHAT_GROUP_MEMBER_JOINED).
+		Take(member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChatGroupNotMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	messageID, err = s.ackMessageID(messageID, s.db.Where("group_id = ?", groupID))
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &vo.ChatReceiptVO{GroupID: groupID, To: accountID, MessageID: messageID, Status: status}
+	updates := map[string]interface{}{
+		"delivered_message_id": max(member.DeliveredMessageID, messageID),
+	}
+	if status == models.Read {
+		readID := max(member.ReadMessageID, messageID)
+		var unread int64
+		if err = s.db.Model(&models.Message{}).
+			Where("group_id = ? AND id > ? AND `from` <> ?", groupID, readID, accountID).
+			Count(&unread).Error; err != nil {
+			return nil, err
+		}
+		updates["read_message_id"] = readID
+		updates["unread_count"] = unread
+	}
+	if err = s.db.Model(member).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// Undelivered 发给用户但还没有任何设备确认送达的单聊和群聊消息，上线时补发
+func (s *ChatService) Undelivered(accountID uint) ([]models.Message, error) {
+	msgs := make([]models.Message, 0)
+	if err := s.db.Where("`to` = ? AND status = ?", accountID, models.Sent).
+		Order("id ASC").Limit(constants.CHAT_SYNC_LIMIT).Find(&msgs).Error; err != nil {
+		return nil, err
+	}
+	groupMsgs := make([]models.Message, 0)
+	if err := s.db.Model(&models.Message{}).Select("message.*").
+		Joins("JOIN chat_group_member AS m ON m.group_id = message.group_id AND m.account_id = ? AND m.status = ?",
+			accountID, constants.CHAT_GROUP_MEMBER_JOINED).
+		Where("message.id > m.delivered_message_id AND message.from <> ?", accountID).
+		Order("message.id ASC").Limit(constants.CHAT_SYNC_LIMIT).Find(&groupMsgs).Error; err != nil {
+		return nil, err
+	}
+	msgs = append(msgs, groupMsgs...)
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].ID < msgs[j].ID })
+	return msgs[:min(len(msgs), constants.CHAT_SYNC_LIMIT)], nil
+}
+
+// Sync 用户收发的单聊消息和所在群的消息中 ID 大于 lastMessageID 的，用于设备重连后补齐
+func (s *ChatService) Sync(accountID, lastMessageID uint) ([]models.Message, error) {
+	groupIDs := s.db.Model(&models.ChatGroupMember{}).Select("group_id").
+		Where("account_id = ? AND status = ?", accountID, constants.CHAT_GROUP_MEMBER_JOINED)
+	msgs := make([]models.Message, 0)
+	err := notDeletedBy(s.db, accountID).Where("(`from` = ? OR `to` = ? OR group_id IN (?)) AND id > ?", accountID, accountID, groupIDs, lastMessageID).
+		Order("id ASC").Limit(constants.CHAT_SYNC_LIMIT).Find(&msgs).Error
+	return msgs, err
+}
+
+// Record 和 contactID 的单聊记录，不包含自己删除的消息；messageID 不为 0 时返回该消息之前的记录，用于上滑加载
+func (s *ChatService) Record(accountID, contactID, messageID uint, size int) ([]models.Message, error) {
+	query := notDeletedBy(s.db, accountID).
+		Where("((`from` = ? AND `to` = ?) OR (`from` = ? AND `to` = ?))", accountID, contactID, contactID, accountID)
+	if messageID != 0 {
+		query = query.Where("id < ?", messageID)
+	}
+	msgs := make([]models.Message, 0)
+	err := query.Order("time desc").Limit(size).Find(&msgs).Error
+	return msgs, err
+}
+
+// 排除 accountID 在自己这一侧删除的消息
+func notDeletedBy(db *gorm.DB, accountID uint) *gorm.DB {
+	return db.Where("NOT EXISTS (SELECT 1 FROM message_deletion AS d WHERE d.message_id = message.id AND d.account_id = ?)", accountID)
+}
+
+// Recall 发送方在时限内撤回消息，内容清空，接收方还没读的不再计入未读数
+func (s *ChatService) Recall(accountID, messageID uint) (*models.Message, error) {
+	msg, err := s.ownMessage(accountID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	window := time.Duration(utils.WithDefault(config.Config.Chat.RecallWindow, constants.CHAT_DEFAULT_RECALL_WINDOW)) * time.Second
+	if time.Since(msg.Time) > window {
+		return nil, ErrMessageRecallExpired
+	}
+
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(msg).Where("recalled_at IS NULL").
+			Updates(map[string]interface{}{"content": "", "duration": 0, "recalled_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMessageRecalled
+		}
+		if msg.GroupID != 0 {
+			return tx.Model(&models.ChatGroupMember{}).
+				Where("group_id = ? AND account_id <> ? AND status = ? AND read_message_id < ? AND unread_count > 0",
+					msg.GroupID, accountID, constants.CHAT_GROUP_MEMBER_JOINED, msg.ID).
+				Update("unread_count", gorm.Expr("unread_count - 1")).Error
+		}
+		if msg.To == msg.From || msg.Status == models.Read {
+			return nil
+		}
+		return tx.Model(&models.ContactList{}).
+			Where("account_id = ? AND contact_id = ? AND unread_count > 0", msg.To, msg.From).
+			Update("unread_count", gorm.Expr("unread_count - 1")).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg.Content = ""
+	msg.Duration = 0
+	msg.RecalledAt = &now
+	return msg, nil
+}
+
+// Edit 发送方编辑文本消息，记录编辑时间
+func (s *ChatService) Edit(accountID, messageID uint, content string) (*models.Message, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrMessageContentInvalid
+	}
+	if utf8.RuneCountInString(content) > constants.CHAT_TEXT_MAX_LENGTH {
+		return nil, ErrMessageTooLong
+	}
+	msg, err := s.ownMessage(accountID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Type != models.Text {
+		return nil, ErrMessageNotEditable
+	}
+	now := time.Now()
+	result := s.db.Model(msg).Where("recalled_at IS NULL").
+		Updates(map[string]interface{}{"content": content, "edited_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrMessageRecalled
+	}
+	msg.Content = content
+	msg.EditedAt = &now
+	return msg, nil
+}
+
+// Delete 在 accountID 这一侧删除消息，重复删除直接忽略
+func (s *ChatService) Delete(accountID, messageID uint) (*models.Message, error) {
+	msg, err := s.message(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.GroupID != 0 {
+		if err = s.checkJoined(accountID, msg.GroupID); err != nil {
+			return nil, err
+		}
+	} else if msg.From != accountID && msg.To != accountID {
+		// 不暴露别人的消息是否存在
+		return nil, ErrMessageNotExist
+	}
+	err = s.db.Create(&models.MessageDeletion{MessageID: msg.ID, AccountID: accountID}).Error
+	if err != nil && !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// 自己发出且没有撤回的消息，群消息还要求仍在群里。消息经 kafka 异步落库，刚发出的消息可能还查不到
+func (s *ChatService) ownMessage(accountID, messageID uint) (*models.Message, error) {
+	msg, err := s.message(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.From != accountID {
+		return nil, ErrMessageNotSender
+	}
+	if msg.RecalledAt != nil {
+		return nil, ErrMessageRecalled
+	}
+	if msg.GroupID != 0 {
+		if err = s.checkJoined(accountID, msg.GroupID); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+func (s *ChatService) message(messageID uint) (*models.Message, error) {
+	msg := &models.Message{}
+	err := s.db.Take(msg, messageID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMessageNotExist
+	}
+	return msg, err
+}
+
+func (s *ChatService) checkJoined(accountID, groupID uint) error {
+	var count int64
+	if err := s.db.Model(&models.ChatGroupMember{}).
+		Where("group_id = ? AND account_id = ? AND status = ?", groupID, accountID, constants.CHAT_GROUP_MEMBER_JOINED).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrChatGroupNotMember
+	}
+	return nil
+}
+
+// MessagePreview 会话列表中的最后一条消息预览
+func MessagePreview(msgType models.MessageType, content string, recalled bool) string {
+	if recalled {
+		return constants.CHAT_PREVIEW_RECALL
+	}
+	switch msgType {
+	case models.Image:
+		return constants.CHAT_PREVIEW_IMAGE
+	case models.File:
+		return constants.CHAT_PREVIEW_FILE
+	case models.Voice:
+		return constants.CHAT_PREVIEW_VOICE
+	}
+	if utf8.RuneCountInString(content) > constants.CHAT_PREVIEW_LENGTH {
+		return string([]rune(content)[:constants.CHAT_PREVIEW_LENGTH]) + "..."
+	}
+	return content
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/factories"
+	"elderly-care-backend/dto"
+	"elderly-care-backend/models"
+	"elderly-care-backend/utils"
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ErrMessageTypeInvalid   = FrameError(constants.MESSAGE_TYPE_INVALID)
+	ErrMessageTooLong       = FrameError(constants.MESSAGE_TOO_LONG)
+	ErrMessageURLInvalid    = FrameError(constants.MESSAGE_URL_INVALID)
+	ErrChatRecipientInvalid = FrameError(constants.CHAT_RECIPIENT_NOT_EXIST)
+	ErrChatNoPermission     = FrameError(constants.NO_PERMISSION)
+)
+
+// Validate 校验客户端发来的消息并生成待发送的消息：类型、内容长度、图片和文件地址必须是本服务上传的，
+// 单聊对象必须存在且和发送方有关联。群聊成员在投递时校验，语音时长由 ChatVoiceService 填写
+func (s *ChatService) Validate(accountID uint, req *dto.MessageDTO) (*models.Message, error) {
+	if (req.To == 0) == (req.GroupID == 0) {
+		return nil, ErrChatRecipientInvalid
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, ErrMessageContentInvalid
+	}
+	switch req.Type {
+	case models.Text:
+		if utf8.RuneCountInString(req.Content) > constants.CHAT_TEXT_MAX_LENGTH {
+			return nil, ErrMessageTooLong
+		}
+	case models.Image, models.File, models.Voice:
+		if len(req.Content) > constants.CHAT_URL_MAX_LENGTH {
+			return nil, ErrMessageTooLong
+		}
+		// 语音地址在取时长时按上传记录校验
+		if req.Type != models.Voice && !isUploadedFileURL(req.Content, req.Type == models.Image) {
+			return nil, ErrMessageURLInvalid
+		}
+	default:
+		return nil, ErrMessageTypeInvalid
+	}
+
+	if req.GroupID == 0 {
+		if err := s.checkRecipient(accountID, req.To); err != nil {
+			return nil, err
+		}
+	}
+	return &models.Message{
+		From:    accountID,
+		To:      req.To,
+		GroupID: req.GroupID,
+		Content: req.Content,
+		Type:    req.Type,
+	}, nil
+}
+
+// 通过 /file/upload 上传到文件桶的地址，不能带查询参数或跳出桶的路径
+func isUploadedFileURL(rawURL string, image bool) bool {
+	client := factories.OssClientFactory.GetOssClient(factories.MINIO)
+	if client == nil {
+		return false
+	}
+	prefix := client.GetServiceUrl() + "/" + factories.FILE_BUCKET + "/"
+	if !strings.HasPrefix(rawURL, prefix) {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery != "" || u.Fragment != "" || path.Clean(u.Path) != u.Path {
+		return false
+	}
+	objectName := strings.TrimPrefix(rawURL, prefix)
+	if objectName == "" || strings.Contains(objectName, "/") {
+		return false
+	}
+	return !image || utils.IsImageFile(objectName)
+}
+
+// 单聊对象必须是未注销的用户，并且和发送方聊过天、是监护关系、有过同一个任务或在同一个群里；
+// 管理员和运营人员可以联系任何人，任何人也可以联系他们
+func (s *ChatService) checkRecipient(accountID, to uint) error {
+	if to == accountID {
+		return nil
+	}
+	accounts := make([]models.Account, 0, 2)
+	if err := s.db.Select("id", "role", "status").Where("id IN ?", []uint{accountID, to}).Find(&accounts).Error; err != nil {
+		return err
+	}
+	var recipient *models.Account
+	staff := false
+	for i := range accounts {
+		if accounts[i].ID == to {
+			recipient = &accounts[i]
+		}
+		if accounts[i].Role == constants.ROLE_ADMIN || accounts[i].Role == constants.ROLE_OPERATOR {
+			staff = true
+		}
+	}
+	if recipient == nil || recipient.Status == constants.ACCOUNT_STATUS_DELETED {
+		return ErrChatRecipientInvalid
+	}
+	if staff {
+		return nil
+	}
+
+	related := []*gorm.DB{
+		s.db.Model(&models.ContactList{}).Where("account_id = ? AND contact_id = ?", to, accountID),
+		s.db.Model(&models.Guardian{}).
+			Where("((elder_id = ? AND guardian_id = ?) OR (elder_id = ? AND guardian_id = ?)) AND status = ?",
+				accountID, to, to, accountID, constants.GUARDIAN_STATUS_VERIFIED),
+		s.db.Model(&models.Task{}).
+			Where("((creator_id = ? AND assignee_id = ?) OR (creator_id = ? AND assignee_id = ?))", accountID, to, to, accountID),
+		s.db.Table("chat_group_member AS a").
+			Joins("JOIN chat_group_member AS b ON b.group_id = a.group_id").
+			Where("a.account_id = ? AND a.status = ? AND b.account_id = ? AND b.status = ?",
+				accountID, constants.CHAT_GROUP_MEMBER_JOINED, to, constants.CHAT_GROUP_MEMBER_JOINED),
+	}
+	for _, query := range related {
+		var count int64
+		if err := query.Limit(1).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+	}
+	return ErrChatNoPermission
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/factories"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrVoiceFormat   = errors.New(constants.FILE_FORMAT_ERROR)
+	ErrVoiceOpen     = errors.New(constants.FILE_OPEN_ERROR)
+	ErrVoiceTooLarge = errors.New(constants.VOICE_TOO_LARGE)
+	ErrVoiceTooShort = errors.New(constants.VOICE_TOO_SHORT)
+	ErrVoiceTooLong  = errors.New(constants.VOICE_TOO_LONG)
+	ErrVoiceUpload   = errors.New(constants.UPLOAD_ERROR)
+)
+
+const ErrVoiceNotUploaded = FrameError(constants.VOICE_NOT_UPLOADED)
+
+// ChatVoiceService 语音消息：上传时在服务端校验格式、计算时长，发送时使用上传时记录的时长，不信任客户端传来的值
+type ChatVoiceService struct {
+	redisClient *redis.Client
+}
+
+func NewChatVoiceService(redisClient *redis.Client) *ChatVoiceService {
+	return &ChatVoiceService{redisClient: redisClient}
+}
+
+// 内存中的音频，解码器关闭时会关闭底层文件，这里不需要
+type audioBuffer struct {
+	*bytes.Reader
+}
+
+func (audioBuffer) Close() error {
+	return nil
+}
+
+// Upload 校验并上传语音，返回地址和时长(秒)，记录上传人以便发送时核对
+func (s *ChatVoiceService) Upload(accountID uint, fileHeader *multipart.FileHeader) (*vo.ChatVoiceVO, error) {
+	if !utils.IsMusicFile(fileHeader.Filename) {
+		return nil, ErrVoiceFormat
+	}
+	if fileHeader.Size > constants.CHAT_VOICE_MAX_SIZE {
+		return nil, ErrVoiceTooLarge
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, ErrVoiceOpen
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, constants.CHAT_VOICE_MAX_SIZE+1))
+	if err != nil {
+		return nil, ErrVoiceOpen
+	}
+	if len(data) > constants.CHAT_VOICE_MAX_SIZE {
+		return nil, ErrVoiceTooLarge
+	}
+
+	ext := strings.ToLower(path.Ext(fileHeader.Filename))
+	// 解码失败说明内容和扩展名不符
+	length, err := utils.GetAudioDuration(audioBuffer{bytes.NewReader(data)}, ext)
+	if err != nil {
+		return nil, ErrVoiceFormat
+	}
+	duration := int(math.Round(time.Duration(length).Seconds()))
+	if duration < constants.CHAT_VOICE_MIN_DURATION {
+		return nil, ErrVoiceTooShort
+	}
+	if duration > constants.CHAT_VOICE_MAX_DURATION {
+		return nil, ErrVoiceTooLong
+	}
+
+	client := factories.OssClientFactory.GetOssClient(factories.MINIO)
+	objectName := constants.CHAT_VOICE_DIR + uuid.NewString() + ext
+	url, err := client.Upload(factories.FILE_BUCKET, objectName, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrVoiceUpload
+	}
+	value := fmt.Sprintf("%d:%d", accountID, duration)
+	if err = s.redisClient.Set(context.Background(), constants.CHAT_VOICE_PREFIX+url, value, constants.CHAT_VOICE_TTL).Err(); err != nil {
+		return nil, err
+	}
+	return &vo.ChatVoiceVO{Url: url, Duration: duration}, nil
+}
+
+// Duration 发送语音消息时取上传时计算的时长，只能发送自己上传且未过期的语音
+func (s *ChatVoiceService) Duration(accountID uint, url string) (int, error) {
+	value, err := s.redisClient.Get(context.Background(), constants.CHAT_VOICE_PREFIX+url).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrVoiceNotUploaded
+	}
+	if err != nil {
+		return 0, err
+	}
+	var uploader uint
+	var duration int
+	if _, err = fmt.Sscanf(value, "%d:%d", &uploader, &duration); err != nil || uploader != accountID {
+		return 0, ErrVoiceNotUploaded
+	}
+	return duration, nil
+}
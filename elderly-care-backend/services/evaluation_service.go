@@ -0,0 +1,60 @@
+package services
+
+import (
+	"elderly-care-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type EvaluationService struct {
+	db *gorm.DB
+}
+
+func NewEvaluationService(db *gorm.DB) *EvaluationService {
+	return &EvaluationService{db: db}
+}
+
+// 评价汇总的查询结果
+type evaluationStat struct {
+	Total      float64
+	Count      int
+	Star1Count int
+	Star2Count int
+	Star3Count int
+	Star4Count int
+	Star5Count int
+}
+
+// RecomputeAccountEvaluation 根据任务评价重新计算账号的评价汇总
+func (s *EvaluationService) RecomputeAccountEvaluation(tx *gorm.DB, accountID uint) error {
+	var stat evaluationStat
+	if err := tx.Model(&models.TaskEvaluation{}).
+		Select(`COALESCE(SUM(score), 0) AS total, COUNT(*) AS count,
+			SUM(CASE WHEN score = 1 THEN 1 ELSE 0 END) AS star1_count,
+			SUM(CASE WHEN score = 2 THEN 1 ELSE 0 END) AS star2_count,
+			SUM(CASE WHEN score = 3 THEN 1 ELSE 0 END) AS star3_count,
+			SUM(CASE WHEN score = 4 THEN 1 ELSE 0 END) AS star4_count,
+			SUM(CASE WHEN score = 5 THEN 1 ELSE 0 END) AS star5_count`).
+		Where("ratee_id = ?", accountID).
+		Scan(&stat).Error; err != nil {
+		return err
+	}
+
+	var average float64
+	if stat.Count > 0 {
+		average = stat.Total / float64(stat.Count)
+	}
+	evaluation := &models.AccountEvaluation{}
+	return tx.Where(models.AccountEvaluation{AccountID: accountID}).
+		Assign(map[string]interface{}{
+			"score":         stat.Total,
+			"assign_count":  stat.Count,
+			"average_score": average,
+			"star1_count":   stat.Star1Count,
+			"star2_count":   stat.Star2Count,
+			"star3_count":   stat.Star3Count,
+			"star4_count":   stat.Star4Count,
+			"star5_count":   stat.Star5Count,
+		}).
+		FirstOrCreate(evaluation).Error
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type GuardianService struct {
+	db *gorm.DB
+}
+
+func NewGuardianService(db *gorm.DB) *GuardianService {
+	return &GuardianService{db: db}
+}
+
+// GetVerifiedGuardianIDs 获取老人已确认的监护人，按通知优先级排序
+func (s *GuardianService) GetVerifiedGuardianIDs(elderID uint) ([]uint, error) {
+	var ids []uint
+	err := s.db.Model(&models.Guardian{}).
+		Where("elder_id = ? AND status = ?", elderID, constants.GUARDIAN_STATUS_VERIFIED).
+		Order("priority ASC, id ASC").
+		Pluck("guardian_id", &ids).Error
+	return ids, err
+}
+
+// IsGuardian 判断是否为老人已确认的监护人
+func (s *GuardianService) IsGuardian(guardianID, elderID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.Guardian{}).
+		Where("elder_id = ? AND guardian_id = ? AND status = ?", elderID, guardianID, constants.GUARDIAN_STATUS_VERIFIED).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// 位置共享给接单志愿者的任务状态，任务结束后不再共享
+var locationSharingTaskStatuses = []string{
+	constants.TASK_STATUS_ASSIGNED,
+	constants.TASK_STATUS_IN_PROGRESS,
+}
+
+// LocationPrivacyService 位置共享权限：本人和已确认的监护人始终可见，
+// 接单志愿者只在任务进行中可见发布人的位置，其他人不可见
+type LocationPrivacyService struct {
+	db *gorm.DB
+}
+
+func NewLocationPrivacyService(db *gorm.DB) *LocationPrivacyService {
+	return &LocationPrivacyService{db: db}
+}
+
+// CanView 判断 viewerID 能否查看 ownerID 的位置
+func (s *LocationPrivacyService) CanView(viewerID, ownerID uint) (bool, error) {
+	if viewerID == ownerID {
+		return true, nil
+	}
+	var count int64
+	if err := s.db.Model(&models.Guardian{}).
+		Where("elder_id = ? AND guardian_id = ? AND status = ?", ownerID, viewerID, constants.GUARDIAN_STATUS_VERIFIED).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	err := s.db.Model(&models.Task{}).
+		Where("creator_id = ? AND assignee_id = ? AND status IN ?", ownerID, viewerID, locationSharingTaskStatuses).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Viewers 获取可以查看 ownerID 位置的其他用户，用于推送位置更新
+func (s *LocationPrivacyService) Viewers(ownerID uint) ([]uint, error) {
+	var guardianIDs, assigneeIDs []uint
+	if err := s.db.Model(&models.Guardian{}).
+		Where("elder_id = ? AND status = ?", ownerID, constants.GUARDIAN_STATUS_VERIFIED).
+		Pluck("guardian_id", &guardianIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.Task{}).
+		Where("creator_id = ? AND assignee_id IS NOT NULL AND status IN ?", ownerID, locationSharingTaskStatuses).
+		Distinct().Pluck("assignee_id", &assigneeIDs).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint]bool)
+	viewers := make([]uint, 0, len(guardianIDs)+len(assigneeIDs))
+	for _, id := range append(guardianIDs, assigneeIDs...) {
+		if id != ownerID && !seen[id] {
+			seen[id] = true
+			viewers = append(viewers, id)
+		}
+	}
+	return viewers, nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// LoginBlockedError 登录被限制，Code 为返回给前端的错误码
+type LoginBlockedError struct {
+	Code       string
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return e.Code
+}
+
+// LoginAttemptInfo 一次登录尝试的上下文，用于写审计日志
+type LoginAttemptInfo struct {
+	Phone     string
+	IP        string
+	UserAgent string
+	Method    string
+}
+
+// LoginGuardService 按手机号和IP统计登录失败次数，实现退避等待和临时锁定
+type LoginGuardService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+func NewLoginGuardService(db *gorm.DB, redisClient *redis.Client) *LoginGuardService {
+	return &LoginGuardService{db: db, redisClient: redisClient}
+}
+
+// Check 登录前检查手机号和IP是否被锁定或处于退避等待中
+func (s *LoginGuardService) Check(ctx context.Context, phone, ip string) error {
+	keys := []string{
+		constants.LOGIN_LOCK_PREFIX + "phone:" + phone,
+		constants.LOGIN_LOCK_PREFIX + "ip:" + ip,
+		constants.LOGIN_BACKOFF_PREFIX + "phone:" + phone,
+		constants.LOGIN_BACKOFF_PREFIX + "ip:" + ip,
+	}
+	pipe := s.redisClient.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	for i, ttl := range ttls {
+		if ttl.Val() <= 0 {
+			continue
+		}
+		code := constants.LOGIN_TOO_FREQUENT
+		if i < 2 {
+			code = constants.ACCOUNT_LOCKED
+		}
+		return &LoginBlockedError{Code: code, RetryAfter: ttl.Val()}
+	}
+	return nil
+}
+
+// RecordFailure 记录一次失败，超过免费次数后退避等待时间翻倍，达到上限后锁定
+func (s *LoginGuardService) RecordFailure(ctx context.Context, attempt LoginAttemptInfo, accountID *uint, reason string) {
+	s.audit(attempt, accountID, false, reason)
+
+	s.recordFailure(ctx, "phone:"+attempt.Phone, constants.LOGIN_PHONE_MAX_FAILURES)
+	s.recordFailure(ctx, "ip:"+attempt.IP, constants.LOGIN_IP_MAX_FAILURES)
+}
+
+func (s *LoginGuardService) recordFailure(ctx context.Context, subject string, maxFailures int64) {
+	failKey := constants.LOGIN_FAIL_PREFIX + subject
+	var count *redis.IntCmd
+	if _, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, failKey)
+		pipe.Expire(ctx, failKey, constants.LOGIN_FAIL_WINDOW)
+		return nil
+	}); err != nil {
+		global.Logger.Error("record login failure error", zap.String("subject", subject), zap.Error(err))
+		return
+	}
+
+	failures := count.Val()
+	switch {
+	case failures >= maxFailures:
+		s.redisClient.Set(ctx, constants.LOGIN_LOCK_PREFIX+subject, failures, constants.LOGIN_LOCK_DURATION)
+		s.redisClient.Del(ctx, failKey)
+		global.Logger.Warn("login locked", zap.String("subject", subject), zap.Int64("failures", failures))
+	case failures > constants.LOGIN_FREE_ATTEMPTS:
+		s.redisClient.Set(ctx, constants.LOGIN_BACKOFF_PREFIX+subject, failures,
+			loginBackoff(failures-constants.LOGIN_FREE_ATTEMPTS))
+	}
+}
+
+// RecordSuccess 登录成功后清除该手机号的失败计数，IP的计数保留
+func (s *LoginGuardService) RecordSuccess(ctx context.Context, attempt LoginAttemptInfo, accountID uint) {
+	s.audit(attempt, &accountID, true, "")
+	s.redisClient.Del(ctx,
+		constants.LOGIN_FAIL_PREFIX+"phone:"+attempt.Phone,
+		constants.LOGIN_BACKOFF_PREFIX+"phone:"+attempt.Phone)
+}
+
+// Unlock 管理员解除手机号的锁定
+func (s *LoginGuardService) Unlock(ctx context.Context, phone string) error {
+	return s.redisClient.Del(ctx,
+		constants.LOGIN_FAIL_PREFIX+"phone:"+phone,
+		constants.LOGIN_BACKOFF_PREFIX+"phone:"+phone,
+		constants.LOGIN_LOCK_PREFIX+"phone:"+phone).Err()
+}
+
+// ListAttempts 分页查询登录审计日志，phone 为空时查询全部
+func (s *LoginGuardService) ListAttempts(phone string, page, pageSize int) ([]models.LoginAttempt, int64, error) {
+	query := s.db.Model(&models.LoginAttempt{})
+	if phone != "" {
+		query = query.Where("phone = ?", phone)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var attempts []models.LoginAttempt
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&attempts).Error
+	return attempts, total, err
+}
+
+func (s *LoginGuardService) audit(attempt LoginAttemptInfo, accountID *uint, success bool, reason string) {
+	userAgent := attempt.UserAgent
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	if err := s.db.Create(&models.LoginAttempt{
+		Phone:     attempt.Phone,
+		AccountID: accountID,
+		IP:        attempt.IP,
+		UserAgent: userAgent,
+		Method:    attempt.Method,
+		Success:   success,
+		Reason:    reason,
+	}).Error; err != nil {
+		global.Logger.Error("write login audit error", zap.String("phone", attempt.Phone), zap.Error(err))
+	}
+}
+
+// 第n次退避等待 base*2^(n-1)，不超过上限
+func loginBackoff(n int64) time.Duration {
+	backoff := constants.LOGIN_BACKOFF_BASE
+	for i := int64(1); i < n && backoff < constants.LOGIN_BACKOFF_MAX; i++ {
+		backoff *= 2
+	}
+	if backoff > constants.LOGIN_BACKOFF_MAX {
+		backoff = constants.LOGIN_BACKOFF_MAX
+	}
+	return backoff
+}
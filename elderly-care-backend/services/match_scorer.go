@@ -0,0 +1,143 @@
+package services
+
+import (
+	"elderly-care-backend/config"
+	"elderly-care-backend/models"
+	"math"
+	"time"
+)
+
+// 匹配因子名称，与 config.yaml 中 matching.weights 的键对应
+const (
+	FactorDistance   = "distance"
+	FactorReputation = "reputation"
+	FactorRecency    = "recency"
+	FactorWorkload   = "workload"
+	FactorCategory   = "category"
+)
+
+// 未配置权重时使用的默认值
+var defaultMatchWeights = map[string]float64{
+	FactorDistance:   0.4,
+	FactorReputation: 0.25,
+	FactorRecency:    0.15,
+	FactorWorkload:   0.1,
+	FactorCategory:   0.1,
+}
+
+const defaultMatchMaxDistance = 5000
+
+// MatchCandidate 参与打分的志愿者信息
+type MatchCandidate struct {
+	AccountID          uint
+	Distance           float64    // 与任务的距离(米)
+	AverageScore       float64    // 历史评价平均分
+	RatingCount        int        // 收到评价的数量
+	LastLocationUpdate *time.Time // 最近一次上报位置的时间
+	OpenTasks          int        // 已接单未完成的任务数
+	CategoryCompleted  int        // 已完成的同类任务数
+}
+
+// ScoreFactor 单个匹配因子，返回0-100分
+type ScoreFactor func(task *models.Task, candidate *MatchCandidate) float64
+
+// MatchScorer 志愿者匹配打分，返回总分和各因子得分
+type MatchScorer interface {
+	Score(task *models.Task, candidate *MatchCandidate) (float64, map[string]float64)
+}
+
+// WeightedMatchScorer 按权重加权各匹配因子
+type WeightedMatchScorer struct {
+	factors map[string]ScoreFactor
+	weights map[string]float64
+}
+
+// NewWeightedMatchScorer 使用内置因子创建打分器，weights 为空时使用默认权重
+func NewWeightedMatchScorer(weights map[string]float64, maxDistance float64) *WeightedMatchScorer {
+	if len(weights) == 0 {
+		weights = defaultMatchWeights
+	}
+	if maxDistance <= 0 {
+		maxDistance = defaultMatchMaxDistance
+	}
+	scorer := &WeightedMatchScorer{
+		factors: make(map[string]ScoreFactor),
+		weights: make(map[string]float64),
+	}
+	scorer.RegisterFactor(FactorDistance, distanceFactor(maxDistance), weights[FactorDistance])
+	scorer.RegisterFactor(FactorReputation, reputationFactor, weights[FactorReputation])
+	scorer.RegisterFactor(FactorRecency, recencyFactor, weights[FactorRecency])
+	scorer.RegisterFactor(FactorWorkload, workloadFactor, weights[FactorWorkload])
+	scorer.RegisterFactor(FactorCategory, categoryFactor, weights[FactorCategory])
+	return scorer
+}
+
+// NewConfiguredMatchScorer 根据 config.yaml 的 matching 配置创建打分器
+func NewConfiguredMatchScorer() *WeightedMatchScorer {
+	if config.Config == nil {
+		return NewWeightedMatchScorer(nil, 0)
+	}
+	return NewWeightedMatchScorer(config.Config.Matching.Weights, config.Config.Matching.MaxDistance)
+}
+
+// RegisterFactor 注册或替换一个匹配因子
+func (s *WeightedMatchScorer) RegisterFactor(name string, factor ScoreFactor, weight float64) {
+	s.factors[name] = factor
+	s.weights[name] = weight
+}
+
+func (s *WeightedMatchScorer) Score(task *models.Task, candidate *MatchCandidate) (float64, map[string]float64) {
+	breakdown := make(map[string]float64, len(s.factors))
+	var total, weightSum float64
+	for name, factor := range s.factors {
+		weight := s.weights[name]
+		if weight <= 0 {
+			continue
+		}
+		score := math.Round(factor(task, candidate)*100) / 100
+		breakdown[name] = score
+		total += score * weight
+		weightSum += weight
+	}
+	if weightSum > 0 {
+		total /= weightSum
+	}
+	return math.Round(total*100) / 100, breakdown
+}
+
+// 距离越近分数越高
+func distanceFactor(maxDistance float64) ScoreFactor {
+	return func(task *models.Task, candidate *MatchCandidate) float64 {
+		return math.Max(0, 100-(candidate.Distance/maxDistance)*100)
+	}
+}
+
+// 平均分换算为百分制，没有评价的志愿者给中间分，避免新人完全排不上
+func reputationFactor(task *models.Task, candidate *MatchCandidate) float64 {
+	if candidate.RatingCount == 0 {
+		return 60
+	}
+	return candidate.AverageScore / 5 * 100
+}
+
+// 10分钟内上报过位置为满分，24小时内线性衰减
+func recencyFactor(task *models.Task, candidate *MatchCandidate) float64 {
+	if candidate.LastLocationUpdate == nil {
+		return 0
+	}
+	elapsed := time.Since(*candidate.LastLocationUpdate)
+	if elapsed <= 10*time.Minute {
+		return 100
+	}
+	return math.Max(0, 100-elapsed.Hours()/24*100)
+}
+
+// 手上的任务越少分数越高，3个及以上为0
+func workloadFactor(task *models.Task, candidate *MatchCandidate) float64 {
+	return math.Max(0, 100-float64(candidate.OpenTasks)*100/3)
+}
+
+// 完成过同类任务的志愿者优先，4个及以上为满分
+func categoryFactor(task *models.Task, candidate *MatchCandidate) float64 {
+	return math.Min(100, float64(candidate.CategoryCompleted)*25)
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/factories"
+	"elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"elderly-care-backend/utils"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPasswordTooWeak   = errors.New(constants.PASSWORD_TOO_WEAK)
+	ErrResetTokenInvalid = errors.New(constants.RESET_TOKEN_INVALID)
+)
+
+// PasswordResetService 找回密码：验证手机号后签发一次性重置凭证，重置后吊销所有会话
+type PasswordResetService struct {
+	db                *gorm.DB
+	redisClient       *redis.Client
+	tokenService      *TokenService
+	loginGuardService *LoginGuardService
+}
+
+func NewPasswordResetService(db *gorm.DB, redisClient *redis.Client) *PasswordResetService {
+	return &PasswordResetService{
+		db:                db,
+		redisClient:       redisClient,
+		tokenService:      NewTokenService(db, redisClient),
+		loginGuardService: NewLoginGuardService(db, redisClient),
+	}
+}
+
+// IssueResetToken 手机号验证通过后签发重置凭证
+func (s *PasswordResetService) IssueResetToken(ctx context.Context, accountID uint) (string, error) {
+	resetToken := uuid.NewString()
+	err := s.redisClient.Set(ctx, constants.ACCOUNT_RESET_TOKEN_PREFIX+resetToken, accountID,
+		constants.PASSWORD_RESET_TOKEN_TTL).Err()
+	return resetToken, err
+}
+
+// ResetWithToken 使用重置凭证设置新密码，凭证只能使用一次。
+// 先校验密码强度再消耗凭证，密码太弱时用户可以用同一个凭证换个密码重试
+func (s *PasswordResetService) ResetWithToken(ctx context.Context, resetToken, newPassword string) error {
+	key := constants.ACCOUNT_RESET_TOKEN_PREFIX + resetToken
+	accountID, err := s.redisClient.Get(ctx, key).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return ErrResetTokenInvalid
+	}
+	if err != nil {
+		return err
+	}
+	account, err := s.checkNewPassword(uint(accountID), newPassword)
+	if err != nil {
+		return err
+	}
+
+	// 并发使用同一个凭证时只有删除成功的请求可以继续
+	deleted, err := s.redisClient.Del(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrResetTokenInvalid
+	}
+	return s.setPassword(ctx, account, newPassword)
+}
+
+// ResetPassword 校验密码强度后设置新密码，吊销所有会话并解除登录锁定
+func (s *PasswordResetService) ResetPassword(ctx context.Context, accountID uint, newPassword string) error {
+	account, err := s.checkNewPassword(accountID, newPassword)
+	if err != nil {
+		return err
+	}
+	return s.setPassword(ctx, account, newPassword)
+}
+
+func (s *PasswordResetService) checkNewPassword(accountID uint, newPassword string) (*models.Account, error) {
+	account := &models.Account{}
+	if err := s.db.Select("id", "phone").Take(account, accountID).Error; err != nil {
+		return nil, err
+	}
+	if !utils.CheckPasswordStrength(newPassword, account.Phone) {
+		return nil, ErrPasswordTooWeak
+	}
+	return account, nil
+}
+
+func (s *PasswordResetService) setPassword(ctx context.Context, account *models.Account, newPassword string) error {
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err = s.db.Model(account).Update("password", hashedPassword).Error; err != nil {
+		return err
+	}
+	if err = s.tokenService.RevokeAll(ctx, account.ID); err != nil {
+		return err
+	}
+	return s.loginGuardService.Unlock(ctx, account.Phone)
+}
+
+// AssistedReset 监护人为老人重置密码，并短信通知老人
+func (s *PasswordResetService) AssistedReset(ctx context.Context, guardianID, elderID uint, newPassword string) error {
+	if err := s.ResetPassword(ctx, elderID, newPassword); err != nil {
+		return err
+	}
+
+	var guardian, elder models.Account
+	if err := s.db.Select("id", "nickname").Take(&guardian, guardianID).Error; err != nil {
+		return err
+	}
+	if err := s.db.Select("id", "phone").Take(&elder, elderID).Error; err != nil {
+		return err
+	}
+	global.Logger.Info("guardian assisted password reset",
+		zap.Uint("guardian_id", guardianID), zap.Uint("elder_id", elderID))
+
+	content := "您的监护人" + guardian.Nickname + "已为您重置登录密码，其他设备需重新登录。如有疑问请联系监护人。"
+	if err := factories.SmsSenderFactory.GetSmsSender().Send(elder.Phone, content); err != nil {
+		// 通知失败不影响重置结果
+		global.Logger.Warn("notify assisted reset error", zap.Uint("elder_id", elderID), zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/config"
+	"elderly-care-backend/utils"
+	"os"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+)
+
+// RealtimeBroker 在网关节点之间转发推送事件，每个节点都会收到全部事件（包括自己发出的）
+type RealtimeBroker interface {
+	Publish(ctx context.Context, message []byte) error
+	// Subscribe 阻塞接收事件，直到 ctx 结束或连接断开
+	Subscribe(ctx context.Context, handler func(message []byte)) error
+}
+
+// RealtimeCluster 网关所在节点，以及节点之间的事件转发和在线状态
+type RealtimeCluster struct {
+	NodeID   string
+	Broker   RealtimeBroker
+	Presence PresenceStore
+}
+
+// NewRealtimeCluster 按配置创建，memory 只适合单实例部署，在线状态也只记录在进程内，其余情况记录在 Redis 中
+func NewRealtimeCluster(redisClient *redis.Client) *RealtimeCluster {
+	realtime := config.Config.Realtime
+	nodeID := utils.WithDefault(realtime.NodeID, defaultNodeID())
+
+	cluster := &RealtimeCluster{
+		NodeID:   nodeID,
+		Presence: NewRedisPresence(redisClient),
+	}
+	switch realtime.Broker {
+	case constants.WS_BROKER_KAFKA:
+		cluster.Broker = NewKafkaBroker(config.Config.Kafka.Addresses, nodeID)
+	case constants.WS_BROKER_MEMORY:
+		cluster.Broker = NewMemoryBroker()
+		cluster.Presence = NewMemoryPresence()
+	default:
+		cluster.Broker = NewRedisBroker(redisClient)
+	}
+	return cluster
+}
+
+// 默认使用主机名，重启后不变，Kafka 消费组可以沿用而不会每次重启留下一个废弃的组；
+// 同一台机器上运行多个实例时需要分别配置 node_id
+func defaultNodeID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "node"
+	}
+	return hostname
+}
+
+// RedisBroker 基于 Redis 发布订阅，订阅断开期间的事件会丢失
+type RedisBroker struct {
+	redisClient *redis.Client
+}
+
+func NewRedisBroker(redisClient *redis.Client) *RedisBroker {
+	return &RedisBroker{redisClient: redisClient}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, message []byte) error {
+	return b.redisClient.Publish(ctx, constants.WS_BROKER_CHANNEL, message).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, handler func(message []byte)) error {
+	pubsub := b.redisClient.Subscribe(ctx, constants.WS_BROKER_CHANNEL)
+	defer pubsub.Close()
+	// 等待订阅确认，Redis 不可用时直接返回错误
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-pubsub.Channel():
+			if !ok {
+				return redis.ErrClosed
+			}
+			handler([]byte(msg.Payload))
+		}
+	}
+}
+
+// KafkaBroker 基于 Kafka，每个节点使用以节点标识命名的消费组，新节点从最新位置开始消费，重启后从上次提交的位置继续
+type KafkaBroker struct {
+	writer *kafka.Writer
+	reader *kafka.Reader
+}
+
+func NewKafkaBroker(addresses []string, nodeID string) *KafkaBroker {
+	return &KafkaBroker{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(addresses...),
+			Topic:        constants.REALTIME_EVENT_TOPIC,
+			Balancer:     &kafka.LeastBytes{},
+			RequiredAcks: kafka.RequireOne,
+		},
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     addresses,
+			Topic:       constants.REALTIME_EVENT_TOPIC,
+			GroupID:     constants.REALTIME_EVENT_GROUP_PREFIX + nodeID,
+			StartOffset: kafka.LastOffset,
+		}),
+	}
+}
+
+func (b *KafkaBroker) Publish(ctx context.Context, message []byte) error {
+	return b.writer.WriteMessages(ctx, kafka.Message{Value: message})
+}
+
+func (b *KafkaBroker) Subscribe(ctx context.Context, handler func(message []byte)) error {
+	for {
+		msg, err := b.reader.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		handler(msg.Value)
+	}
+}
+
+// MemoryBroker 进程内转发，同一进程中的多个网关共用一个实例即可模拟多节点部署
+type MemoryBroker struct {
+	subscribers map[chan []byte]bool
+	mutex       sync.RWMutex
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[chan []byte]bool)}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, message []byte) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- message:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, handler func(message []byte)) error {
+	ch := make(chan []byte, 256)
+	b.mutex.Lock()
+	b.subscribers[ch] = true
+	b.mutex.Unlock()
+	defer func() {
+		b.mutex.Lock()
+		delete(b.subscribers, ch)
+		b.mutex.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case message := <-ch:
+			handler(message)
+		}
+	}
+}
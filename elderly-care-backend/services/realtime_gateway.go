@@ -0,0 +1,658 @@
+package services
+
+import (
+	"context"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/config"
+	"elderly-care-backend/dto/account_dto"
+	"elderly-care-backend/global"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// FrameError 以错误帧返回给客户端的错误码，其他错误统一返回 SERVICE ERROR
+type FrameError string
+
+func (e FrameError) Error() string {
+	return string(e)
+}
+
+const ErrFrameInvalid = FrameError(constants.FRAME_INVALID)
+
+// GatewayFrame 客户端发来的帧，type 为业务通道或控制帧类型
+type GatewayFrame struct {
+	ID    string          `json:"id,omitempty"` // 客户端生成的帧ID，回复和错误中原样带回
+	Type  string          `json:"type"`
+	Topic string          `json:"topic,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// FrameHandler 处理客户端发来的某一类帧，reply 不为空时回复给发送的连接，error 以错误帧回复，连接保持
+type FrameHandler func(accountID uint, data json.RawMessage) (reply interface{}, err error)
+
+// ConnectHook 连接建立后执行，返回的事件只发给新建立的连接，用于补发离线期间的数据
+type ConnectHook func(accountID uint) ([]vo.WsEventVO, error)
+
+// 一个设备的连接及其订阅的主题
+type gatewayClient struct {
+	conn      *websocket.Conn
+	accountID uint
+	claims    *account_dto.Claims // 登录token过期或被吊销后断开，客户端需刷新token重连
+	topics    map[string]bool
+	writeMu   sync.Mutex // 同一连接不能并发写
+	done      chan struct{}
+}
+
+func (c *gatewayClient) write(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(constants.WS_WRITE_TIMEOUT))
+	return c.conn.WriteMessage(messageType, data)
+}
+
+func (c *gatewayClient) writeFrame(frame vo.WsEventVO) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	return c.write(websocket.TextMessage, data)
+}
+
+// 节点之间转发的事件，frame 为已序列化的推送帧
+type brokerEnvelope struct {
+	Node  string          `json:"node"`
+	Topic string          `json:"topic"`
+	Frame json.RawMessage `json:"frame"`
+}
+
+// RealtimeGateway 统一的实时推送网关：每个设备一条连接，握手时校验登录token，
+// 聊天、位置、SOS、任务、导航等消息按 type 复用同一连接，按主题投递；
+// 多实例部署时事件经 broker 转发到其他节点，由各节点投递给本机上订阅了主题的连接
+type RealtimeGateway struct {
+	clients    map[*gatewayClient]bool
+	topics     map[string]map[*gatewayClient]bool // 主题 -> 订阅的连接
+	online     map[uint]int                       // 用户 -> 本节点上的连接数
+	handlers   map[string]FrameHandler
+	hooks      []ConnectHook
+	register   chan *gatewayClient
+	unregister chan *gatewayClient
+	mutex      sync.RWMutex
+
+	upgrader       websocket.Upgrader
+	allowedOrigins map[string]bool
+	pingInterval   time.Duration
+	pongTimeout    time.Duration
+	maxMessageSize int64
+
+	cluster      *RealtimeCluster
+	redisClient  *redis.Client
+	tokenService *TokenService
+	privacy      *LocationPrivacyService
+	authorizer   *TopicAuthorizer
+}
+
+func NewRealtimeGateway(db *gorm.DB, redisClient *redis.Client, cluster *RealtimeCluster) *RealtimeGateway {
+	realtime := config.Config.Realtime
+	g := &RealtimeGateway{
+		clients:        make(map[*gatewayClient]bool),
+		topics:         make(map[string]map[*gatewayClient]bool),
+		online:         make(map[uint]int),
+		handlers:       make(map[string]FrameHandler),
+		register:       make(chan *gatewayClient),
+		unregister:     make(chan *gatewayClient),
+		allowedOrigins: make(map[string]bool),
+		pingInterval:   time.Duration(utils.WithDefault(realtime.PingInterval, constants.WS_DEFAULT_PING_INTERVAL)) * time.Second,
+		pongTimeout:    time.Duration(utils.WithDefault(realtime.PongTimeout, constants.WS_DEFAULT_PONG_TIMEOUT)) * time.Second,
+		maxMessageSize: utils.WithDefault(realtime.MaxMessageSize, constants.WS_DEFAULT_MAX_MESSAGE_SIZE),
+		cluster:        cluster,
+		redisClient:    redisClient,
+		tokenService:   NewTokenService(db, redisClient),
+		privacy:        NewLocationPrivacyService(db),
+		authorizer:     NewTopicAuthorizer(db),
+	}
+	for _, origin := range realtime.AllowedOrigins {
+		g.allowedOrigins[strings.TrimRight(strings.TrimSpace(origin), "/")] = true
+	}
+	g.upgrader = websocket.Upgrader{CheckOrigin: g.checkOrigin}
+
+	g.Handle(constants.WS_CHANNEL_LOCATION, g.handleLocationUpdate)
+	g.Handle(constants.WS_CHANNEL_NAVIGATION, g.handleNavigationRequest)
+	return g
+}
+
+// Handle 注册某一类客户端帧的处理函数
+func (g *RealtimeGateway) Handle(frameType string, handler FrameHandler) {
+	g.handlers[frameType] = handler
+}
+
+// OnConnect 注册连接建立后的补发逻辑
+func (g *RealtimeGateway) OnConnect(hook ConnectHook) {
+	g.hooks = append(g.hooks, hook)
+}
+
+// 没有 Origin 的原生客户端放行，浏览器只允许同源和白名单中的来源
+func (g *RealtimeGateway) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || g.allowedOrigins["*"] || g.allowedOrigins[origin] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// Start 维护连接、订阅关系和在线状态，并接收其他节点转发的事件
+func (g *RealtimeGateway) Start() {
+	go g.consumeBroker()
+	go g.refreshPresence()
+
+	ctx := context.Background()
+	for {
+		select {
+		case client := <-g.register:
+			g.mutex.Lock()
+			g.clients[client] = true
+			g.online[client.accountID]++
+			// 个人主题自动订阅
+			g.subscribeLocked(client, UserTopic(client.accountID))
+			g.mutex.Unlock()
+			// 先登记在线再补发离线事件，之后的事件都会转发到本节点
+			if err := g.cluster.Presence.Online(ctx, g.cluster.NodeID, client.accountID); err != nil {
+				global.Logger.Error("mark presence online error", zap.Uint("account_id", client.accountID), zap.Error(err))
+			}
+			log.Printf("用户 %d 已连接", client.accountID)
+			go g.syncOnConnect(client)
+
+		case client := <-g.unregister:
+			g.mutex.Lock()
+			offline := false
+			if _, ok := g.clients[client]; ok {
+				for topic := range client.topics {
+					g.unsubscribeLocked(client, topic)
+				}
+				delete(g.clients, client)
+				if g.online[client.accountID]--; g.online[client.accountID] <= 0 {
+					delete(g.online, client.accountID)
+					offline = true
+				}
+				close(client.done)
+				client.conn.Close()
+				log.Printf("用户 %d 已断开连接", client.accountID)
+			}
+			g.mutex.Unlock()
+			if offline {
+				if err := g.cluster.Presence.Offline(ctx, g.cluster.NodeID, client.accountID); err != nil {
+					global.Logger.Error("mark presence offline error", zap.Uint("account_id", client.accountID), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// 接收 broker 转发的事件并投递给本节点的连接，连接断开后自动重连
+func (g *RealtimeGateway) consumeBroker() {
+	for {
+		err := g.cluster.Broker.Subscribe(context.Background(), func(message []byte) {
+			var envelope brokerEnvelope
+			if err := json.Unmarshal(message, &envelope); err != nil {
+				log.Printf("转发事件解析失败: %v", err)
+				return
+			}
+			// 本节点发出的事件发布时已经投递过
+			if envelope.Node == g.cluster.NodeID {
+				return
+			}
+			g.deliver(envelope.Topic, envelope.Frame)
+		})
+		global.Logger.Error("realtime broker subscription closed", zap.Error(err))
+		time.Sleep(constants.WS_BROKER_RETRY_INTERVAL)
+	}
+}
+
+// 定时续期本节点上在线用户的在线状态
+func (g *RealtimeGateway) refreshPresence() {
+	ticker := time.NewTicker(constants.WS_PRESENCE_REFRESH_INTERVAL)
+	defer ticker.Stop()
+	for range ticker.C {
+		g.mutex.RLock()
+		accountIDs := make([]uint, 0, len(g.online))
+		for accountID := range g.online {
+			accountIDs = append(accountIDs, accountID)
+		}
+		g.mutex.RUnlock()
+		if err := g.cluster.Presence.Online(context.Background(), g.cluster.NodeID, accountIDs...); err != nil {
+			global.Logger.Error("refresh presence error", zap.Error(err))
+		}
+	}
+}
+
+// 从 Authorization 头或 token 查询参数(浏览器无法设置请求头)中读取并校验访问token
+func (g *RealtimeGateway) authenticate(r *http.Request) (*account_dto.Claims, error) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return nil, FrameError(constants.NOT_LOGIN)
+	}
+	claims, err := utils.ParseToken(token, config.Config.Jwt.SecretKey)
+	if err != nil {
+		return nil, FrameError(constants.INVALID_TOKEN)
+	}
+	if err = g.tokenService.CheckAccess(r.Context(), claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ServeHTTP 握手时完成登录校验和来源校验，之后升级为 WebSocket 连接
+func (g *RealtimeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	claims, err := g.authenticate(r)
+	if err != nil {
+		msg := constants.SERVICE_ERROR
+		var frameErr FrameError
+		if errors.Is(err, ErrTokenRevoked) {
+			msg = constants.TOKEN_REVOKED
+		} else if errors.As(err, &frameErr) {
+			msg = frameErr.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusGone)
+		_ = json.NewEncoder(w).Encode(vo.Fail(msg))
+		return
+	}
+
+	conn, err := g.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("WebSocket升级失败:", err)
+		return
+	}
+
+	client := &gatewayClient{
+		conn:      conn,
+		accountID: claims.AccountId,
+		claims:    claims,
+		topics:    make(map[string]bool),
+		done:      make(chan struct{}),
+	}
+	g.register <- client
+	defer func() {
+		g.unregister <- client
+	}()
+	go g.heartbeat(client)
+
+	conn.SetReadLimit(g.maxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(g.pongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(g.pongTimeout))
+	})
+	for {
+		messageType, p, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		// 收到任何数据都说明连接存活
+		_ = conn.SetReadDeadline(time.Now().Add(g.pongTimeout))
+		if messageType == websocket.TextMessage {
+			g.handleFrame(client, p)
+		}
+	}
+}
+
+// 定时发送 ping，token 过期或被吊销（退出登录、修改密码、注销账号）后断开连接
+func (g *RealtimeGateway) heartbeat(client *gatewayClient) {
+	ticker := time.NewTicker(g.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-client.done:
+			return
+		case now := <-ticker.C:
+			if reason := g.checkClientToken(client, now); reason != "" {
+				_ = client.writeFrame(vo.WsEventVO{Type: constants.WS_FRAME_ERROR, Error: reason, Time: now})
+				client.conn.Close()
+				return
+			}
+			if err := client.write(websocket.PingMessage, nil); err != nil {
+				client.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// 返回需要断开连接的原因，token仍然有效时为空。Redis暂时不可用时不断开，下次心跳再检查
+func (g *RealtimeGateway) checkClientToken(client *gatewayClient, now time.Time) string {
+	if now.After(time.Unix(client.claims.ExpiresAt, 0)) {
+		return constants.INVALID_TOKEN
+	}
+	err := g.tokenService.CheckAccess(context.Background(), client.claims)
+	if errors.Is(err, ErrTokenRevoked) {
+		return constants.TOKEN_REVOKED
+	}
+	if err != nil {
+		global.Logger.Warn("check websocket token error", zap.Uint("account_id", client.accountID), zap.Error(err))
+	}
+	return ""
+}
+
+// 按 type 分发客户端帧，格式错误或处理失败只回复错误帧，不断开连接
+func (g *RealtimeGateway) handleFrame(client *gatewayClient, message []byte) {
+	var frame GatewayFrame
+	if err := json.Unmarshal(message, &frame); err != nil {
+		g.replyError(client, &frame, ErrFrameInvalid)
+		return
+	}
+
+	switch frame.Type {
+	case constants.WS_FRAME_PING:
+		g.reply(client, vo.WsEventVO{Type: constants.WS_FRAME_PONG, ID: frame.ID})
+	case constants.WS_FRAME_SUBSCRIBE:
+		g.handleSubscribe(client, &frame)
+	case constants.WS_FRAME_UNSUBSCRIBE:
+		g.mutex.Lock()
+		// 个人主题不能取消
+		if frame.Topic != UserTopic(client.accountID) {
+			g.unsubscribeLocked(client, frame.Topic)
+		}
+		g.mutex.Unlock()
+		g.reply(client, vo.WsEventVO{Type: constants.WS_FRAME_UNSUBSCRIBED, ID: frame.ID, Topic: frame.Topic})
+	default:
+		handler, ok := g.handlers[frame.Type]
+		if !ok {
+			g.replyError(client, &frame, ErrFrameInvalid)
+			return
+		}
+		reply, err := handler(client.accountID, frame.Data)
+		if err != nil {
+			g.replyError(client, &frame, err)
+			return
+		}
+		if reply != nil {
+			g.reply(client, vo.WsEventVO{Type: frame.Type, ID: frame.ID, Data: reply})
+		}
+	}
+}
+
+// 校验权限后订阅主题
+func (g *RealtimeGateway) handleSubscribe(client *gatewayClient, frame *GatewayFrame) {
+	allowed, err := g.authorizer.CanSubscribe(client.accountID, frame.Topic)
+	if err == nil && !allowed {
+		err = FrameError(constants.NO_PERMISSION)
+	}
+	if err != nil {
+		g.replyError(client, frame, err)
+		return
+	}
+
+	g.mutex.Lock()
+	g.subscribeLocked(client, frame.Topic)
+	g.mutex.Unlock()
+	g.reply(client, vo.WsEventVO{Type: constants.WS_FRAME_SUBSCRIBED, ID: frame.ID, Topic: frame.Topic})
+}
+
+func (g *RealtimeGateway) subscribeLocked(client *gatewayClient, topic string) {
+	subscribers, ok := g.topics[topic]
+	if !ok {
+		subscribers = make(map[*gatewayClient]bool)
+		g.topics[topic] = subscribers
+	}
+	subscribers[client] = true
+	client.topics[topic] = true
+}
+
+func (g *RealtimeGateway) unsubscribeLocked(client *gatewayClient, topic string) {
+	if subscribers, ok := g.topics[topic]; ok {
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(g.topics, topic)
+		}
+	}
+	delete(client.topics, topic)
+}
+
+func (g *RealtimeGateway) reply(client *gatewayClient, frame vo.WsEventVO) {
+	frame.Time = time.Now()
+	if err := client.writeFrame(frame); err != nil {
+		client.conn.Close()
+	}
+}
+
+func (g *RealtimeGateway) replyError(client *gatewayClient, frame *GatewayFrame, err error) {
+	code := constants.SERVICE_ERROR
+	var frameErr FrameError
+	if errors.As(err, &frameErr) {
+		code = frameErr.Error()
+	} else {
+		log.Printf("处理 %s 帧失败: %v", frame.Type, err)
+	}
+	g.reply(client, vo.WsEventVO{Type: constants.WS_FRAME_ERROR, ID: frame.ID, Event: frame.Type, Topic: frame.Topic, Error: code})
+}
+
+// 处理位置更新，位置归属以连接的登录用户为准，不信任客户端上报的 user_id
+func (g *RealtimeGateway) handleLocationUpdate(accountID uint, data json.RawMessage) (interface{}, error) {
+	location := make(map[string]interface{})
+	if err := json.Unmarshal(data, &location); err != nil {
+		return nil, ErrFrameInvalid
+	}
+	location["user_id"] = accountID
+	g.PublishLocation(accountID, location)
+	return nil, nil
+}
+
+// 处理导航请求，带 session_id 时结果发布到导航会话主题，会话双方都能收到
+func (g *RealtimeGateway) handleNavigationRequest(accountID uint, data json.RawMessage) (interface{}, error) {
+	var req struct {
+		SessionID uint     `json:"session_id"`
+		StartLat  *float64 `json:"start_lat"`
+		StartLng  *float64 `json:"start_lng"`
+		EndLat    *float64 `json:"end_lat"`
+		EndLng    *float64 `json:"end_lng"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil ||
+		req.StartLat == nil || req.StartLng == nil || req.EndLat == nil || req.EndLng == nil {
+		return nil, FrameError(constants.PARAM_ERROR)
+	}
+
+	var topic string
+	if req.SessionID != 0 {
+		topic = NavigationTopic(req.SessionID)
+		allowed, err := g.authorizer.CanSubscribe(accountID, topic)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, FrameError(constants.NO_PERMISSION)
+		}
+	}
+
+	// 计算导航信息
+	locationService := &RealtimeLocationService{}
+	navigation, err := locationService.CalculateRealTimeNavigation(*req.StartLat, *req.StartLng, *req.EndLat, *req.EndLng)
+	if err != nil {
+		return nil, err
+	}
+	if topic != "" {
+		g.Publish(topic, constants.WS_EVENT_NAVIGATION_RESULT, navigation)
+		return nil, nil
+	}
+	return navigation, nil
+}
+
+// 事件名以通道名开头，如 sos_alert 属于 sos 通道
+func channelOf(eventType string) string {
+	channel, _, _ := strings.Cut(eventType, "_")
+	return channel
+}
+
+// PublishLocation 把 ownerID 的位置推送给本人和有权限查看的用户
+func (g *RealtimeGateway) PublishLocation(ownerID uint, data interface{}) {
+	viewers, err := g.privacy.Viewers(ownerID)
+	if err != nil {
+		log.Println("查询位置共享对象失败:", err)
+		return
+	}
+	for _, accountID := range append([]uint{ownerID}, viewers...) {
+		g.Publish(UserTopic(accountID), constants.WS_EVENT_LOCATION_UPDATED, data)
+	}
+}
+
+// Publish 发布事件给所有节点上订阅了主题的连接
+func (g *RealtimeGateway) Publish(topic, eventType string, data interface{}) {
+	message, err := json.Marshal(newEventFrame(topic, eventType, data))
+	if err != nil {
+		log.Printf("JSON 序列化失败: %v", err)
+		return
+	}
+	g.deliver(topic, message)
+	g.forward(topic, message)
+}
+
+func newEventFrame(topic, eventType string, data interface{}) vo.WsEventVO {
+	return vo.WsEventVO{
+		Type:  channelOf(eventType),
+		Event: eventType,
+		Topic: topic,
+		Data:  data,
+		Time:  time.Now(),
+	}
+}
+
+// 转发给其他节点
+func (g *RealtimeGateway) forward(topic string, message []byte) {
+	envelope, err := json.Marshal(brokerEnvelope{Node: g.cluster.NodeID, Topic: topic, Frame: message})
+	if err != nil {
+		log.Printf("JSON 序列化失败: %v", err)
+		return
+	}
+	if err = g.cluster.Broker.Publish(context.Background(), envelope); err != nil {
+		global.Logger.Error("forward realtime event error", zap.String("topic", topic), zap.Error(err))
+	}
+}
+
+// 投递给本节点上订阅了主题的连接，返回成功投递的连接数
+func (g *RealtimeGateway) deliver(topic string, message []byte) int {
+	g.mutex.RLock()
+	subscribers := make([]*gatewayClient, 0, len(g.topics[topic]))
+	for client := range g.topics[topic] {
+		subscribers = append(subscribers, client)
+	}
+	g.mutex.RUnlock()
+
+	delivered := 0
+	for _, client := range subscribers {
+		// 写失败时关闭连接，读循环退出后自动注销
+		if err := client.write(websocket.TextMessage, message); err != nil {
+			client.conn.Close()
+			continue
+		}
+		delivered++
+	}
+	return delivered
+}
+
+// PushEvent 向指定用户所有节点上的设备推送事件，用户不在任何节点在线时放入离线队列，上线后补发
+func (g *RealtimeGateway) PushEvent(accountID uint, eventType string, data interface{}) {
+	topic := UserTopic(accountID)
+	message, err := json.Marshal(newEventFrame(topic, eventType, data))
+	if err != nil {
+		log.Printf("JSON 序列化失败: %v", err)
+		return
+	}
+	delivered := g.deliver(topic, message)
+
+	nodes, err := g.cluster.Presence.Nodes(context.Background(), accountID)
+	if err != nil {
+		// 无法确认是否在其他节点在线时照常转发，同时入离线队列，宁可重复也不丢
+		global.Logger.Error("load presence error", zap.Uint("account_id", accountID), zap.Error(err))
+		g.forward(topic, message)
+		g.queueOfflineEvent(accountID, message)
+		return
+	}
+	onlineElsewhere := false
+	for _, nodeID := range nodes {
+		if nodeID != g.cluster.NodeID {
+			onlineElsewhere = true
+			break
+		}
+	}
+	if onlineElsewhere {
+		g.forward(topic, message)
+	} else if delivered == 0 {
+		g.queueOfflineEvent(accountID, message)
+	}
+}
+
+func (g *RealtimeGateway) queueOfflineEvent(accountID uint, message []byte) {
+	key := constants.WS_OFFLINE_EVENTS_PREFIX + strconv.Itoa(int(accountID))
+	ctx := context.Background()
+	if _, err := g.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, message)
+		pipe.Expire(ctx, key, constants.WS_OFFLINE_EVENTS_TTL)
+		return nil
+	}); err != nil {
+		global.Logger.Error("queue offline event error", zap.Uint("account_id", accountID), zap.Error(err))
+	}
+}
+
+// 补发离线期间的事件和各业务注册的数据
+func (g *RealtimeGateway) syncOnConnect(client *gatewayClient) {
+	if !g.flushOfflineEvents(client) {
+		return
+	}
+	for _, hook := range g.hooks {
+		events, err := hook(client.accountID)
+		if err != nil {
+			global.Logger.Error("sync on connect error", zap.Uint("account_id", client.accountID), zap.Error(err))
+			continue
+		}
+		for _, event := range events {
+			event.Time = time.Now()
+			if err = client.writeFrame(event); err != nil {
+				client.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// 用户上线后补发离线期间的事件，连接已断开时返回 false
+func (g *RealtimeGateway) flushOfflineEvents(client *gatewayClient) bool {
+	key := constants.WS_OFFLINE_EVENTS_PREFIX + strconv.Itoa(int(client.accountID))
+	ctx := context.Background()
+	var events *redis.StringSliceCmd
+	if _, err := g.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		events = pipe.LRange(ctx, key, 0, -1)
+		pipe.Del(ctx, key)
+		return nil
+	}); err != nil {
+		global.Logger.Error("load offline events error", zap.Uint("account_id", client.accountID), zap.Error(err))
+		return true
+	}
+	for i, event := range events.Val() {
+		if err := client.write(websocket.TextMessage, []byte(event)); err != nil {
+			// 补发失败的事件重新入队，等下次上线
+			client.conn.Close()
+			remaining := make([]interface{}, 0, len(events.Val())-i)
+			for _, e := range events.Val()[i:] {
+				remaining = append(remaining, e)
+			}
+			g.redisClient.RPush(ctx, key, remaining...)
+			return false
+		}
+	}
+	return true
+}
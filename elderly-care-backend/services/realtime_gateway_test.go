@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/config"
+	"elderly-care-backend/dto/account_dto"
+	"elderly-care-backend/global"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const testJwtSecret = "realtime-test"
+
+// 两个网关节点共用 MemoryBroker 和 MemoryPresence，模拟多实例部署
+type testCluster struct {
+	broker   *MemoryBroker
+	presence *MemoryPresence
+	redis    *redis.Client
+}
+
+func newTestCluster(t *testing.T) *testCluster {
+	t.Helper()
+	logger, appConfig := global.Logger, config.Config
+	global.Logger = zap.NewNop()
+	config.Config = &config.AppConfig{}
+	config.Config.Jwt.SecretKey = testJwtSecret
+	t.Cleanup(func() { global.Logger, config.Config = logger, appConfig })
+
+	server := miniredis.RunT(t)
+	return &testCluster{
+		broker:   NewMemoryBroker(),
+		presence: NewMemoryPresence(),
+		redis:    redis.NewClient(&redis.Options{Addr: server.Addr()}),
+	}
+}
+
+func (tc *testCluster) startNode(t *testing.T, nodeID string) (*RealtimeGateway, string) {
+	t.Helper()
+	gateway := NewRealtimeGateway(nil, tc.redis, &RealtimeCluster{
+		NodeID:   nodeID,
+		Broker:   tc.broker,
+		Presence: tc.presence,
+	})
+	go gateway.Start()
+	server := httptest.NewServer(gateway)
+	t.Cleanup(server.Close)
+	return gateway, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// 连接并等待节点登记在线，之后的事件都能投递到这条连接
+func (tc *testCluster) connect(t *testing.T, wsURL, nodeID string, accountID uint) *websocket.Conn {
+	t.Helper()
+	token, err := utils.GenToken(account_dto.Claims{
+		AccountId: accountID,
+		Role:      constants.ROLE_ELDERLY,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   constants.TOKEN_SUBJECT_ACCESS,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	}, testJwtSecret)
+	if err != nil {
+		t.Fatalf("gen token: %v", err)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token="+token, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", nodeID, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	waitFor(t, func() bool {
+		nodes, _ := tc.presence.Nodes(context.Background(), accountID)
+		for _, node := range nodes {
+			if node == nodeID {
+				return true
+			}
+		}
+		return false
+	})
+	return conn
+}
+
+// 等待两个节点都订阅了 broker
+func (tc *testCluster) waitSubscribers(t *testing.T, count int) {
+	t.Helper()
+	waitFor(t, func() bool {
+		tc.broker.mutex.RLock()
+		defer tc.broker.mutex.RUnlock()
+		return len(tc.broker.subscribers) == count
+	})
+}
+
+func waitFor(t *testing.T, ready func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for gateway")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) vo.WsEventVO {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read event: %v", err)
+	}
+	var event vo.WsEventVO
+	if err = json.Unmarshal(message, &event); err != nil {
+		t.Fatalf("decode event %s: %v", message, err)
+	}
+	return event
+}
+
+func TestRealtimeGatewayCrossNodeDelivery(t *testing.T) {
+	tc := newTestCluster(t)
+	nodeA, _ := tc.startNode(t, "node-a")
+	_, urlB := tc.startNode(t, "node-b")
+	tc.waitSubscribers(t, 2)
+
+	conn := tc.connect(t, urlB, "node-b", 7)
+
+	// 主题事件经 broker 转发到订阅者所在的节点
+	nodeA.Publish(UserTopic(7), constants.WS_EVENT_TASK_EXPIRED, map[string]uint{"task_id": 12})
+	event := readEvent(t, conn)
+	if event.Event != constants.WS_EVENT_TASK_EXPIRED || event.Topic != UserTopic(7) {
+		t.Fatalf("published event = %+v", event)
+	}
+
+	// 个人事件按在线状态转发，不进入离线队列
+	nodeA.PushEvent(7, constants.WS_EVENT_SOS_ACCEPTED, map[string]uint{"sos_id": 3})
+	event = readEvent(t, conn)
+	if event.Event != constants.WS_EVENT_SOS_ACCEPTED {
+		t.Fatalf("pushed event = %+v", event)
+	}
+	queued, err := tc.redis.LLen(context.Background(), constants.WS_OFFLINE_EVENTS_PREFIX+"7").Result()
+	if err != nil || queued != 0 {
+		t.Errorf("offline queue = %d, %v; want empty", queued, err)
+	}
+}
+
+func TestRealtimeGatewayQueuesOfflineEvents(t *testing.T) {
+	tc := newTestCluster(t)
+	nodeA, _ := tc.startNode(t, "node-a")
+	_, urlB := tc.startNode(t, "node-b")
+	tc.waitSubscribers(t, 2)
+
+	// 不在任何节点在线时进入离线队列，在另一个节点上线后补发
+	nodeA.PushEvent(8, constants.WS_EVENT_SOS_RESOLVED, map[string]uint{"sos_id": 4})
+	conn := tc.connect(t, urlB, "node-b", 8)
+	event := readEvent(t, conn)
+	if event.Event != constants.WS_EVENT_SOS_RESOLVED {
+		t.Fatalf("offline event = %+v", event)
+	}
+}
+
+func TestNewRealtimeClusterMemoryPresence(t *testing.T) {
+	appConfig := config.Config
+	config.Config = &config.AppConfig{}
+	config.Config.Realtime.Broker = constants.WS_BROKER_MEMORY
+	t.Cleanup(func() { config.Config = appConfig })
+
+	cluster := NewRealtimeCluster(nil)
+	if _, ok := cluster.Presence.(*MemoryPresence); !ok {
+		t.Errorf("presence = %T, want *MemoryPresence", cluster.Presence)
+	}
+	// 节点标识重启后不变，Kafka 消费组才能沿用
+	if hostname, _ := os.Hostname(); hostname != "" && cluster.NodeID != hostname {
+		t.Errorf("node id = %q, want hostname %q", cluster.NodeID, hostname)
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"elderly-care-backend/common/constants"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PresenceStore 记录用户的连接在哪些网关节点上，节点需要定时刷新，宕机的节点过期后自动移除
+type PresenceStore interface {
+	// Online 标记用户在该节点在线，也用于定时续期
+	Online(ctx context.Context, nodeID string, accountIDs ...uint) error
+	// Offline 用户在该节点的最后一个连接断开
+	Offline(ctx context.Context, nodeID string, accountID uint) error
+	// Nodes 用户当前所在的节点
+	Nodes(ctx context.Context, accountID uint) ([]string, error)
+}
+
+// RedisPresence 每个用户一个 hash，field 为节点ID，值为过期时间戳
+type RedisPresence struct {
+	redisClient *redis.Client
+}
+
+func NewRedisPresence(redisClient *redis.Client) *RedisPresence {
+	return &RedisPresence{redisClient: redisClient}
+}
+
+func presenceKey(accountID uint) string {
+	return constants.WS_PRESENCE_PREFIX + strconv.Itoa(int(accountID))
+}
+
+func (p *RedisPresence) Online(ctx context.Context, nodeID string, accountIDs ...uint) error {
+	if len(accountIDs) == 0 {
+		return nil
+	}
+	expiresAt := time.Now().Add(constants.WS_PRESENCE_TTL).Unix()
+	_, err := p.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, accountID := range accountIDs {
+			key := presenceKey(accountID)
+			pipe.HSet(ctx, key, nodeID, expiresAt)
+			pipe.Expire(ctx, key, constants.WS_PRESENCE_TTL)
+		}
+		return nil
+	})
+	return err
+}
+
+func (p *RedisPresence) Offline(ctx context.Context, nodeID string, accountID uint) error {
+	return p.redisClient.HDel(ctx, presenceKey(accountID), nodeID).Err()
+}
+
+func (p *RedisPresence) Nodes(ctx context.Context, accountID uint) ([]string, error) {
+	key := presenceKey(accountID)
+	fields, err := p.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	nodes := make([]string, 0, len(fields))
+	var expired []string
+	for nodeID, value := range fields {
+		expiresAt, _ := strconv.ParseInt(value, 10, 64)
+		if expiresAt <= now {
+			expired = append(expired, nodeID)
+			continue
+		}
+		nodes = append(nodes, nodeID)
+	}
+	// 顺带清理宕机节点留下的记录
+	if len(expired) > 0 {
+		p.redisClient.HDel(ctx, key, expired...)
+	}
+	return nodes, nil
+}
+
+// MemoryPresence 进程内的在线状态，配合 MemoryBroker 使用
+type MemoryPresence struct {
+	nodes map[uint]map[string]time.Time
+	mutex sync.Mutex
+}
+
+func NewMemoryPresence() *MemoryPresence {
+	return &MemoryPresence{nodes: make(map[uint]map[string]time.Time)}
+}
+
+func (p *MemoryPresence) Online(ctx context.Context, nodeID string, accountIDs ...uint) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	expiresAt := time.Now().Add(constants.WS_PRESENCE_TTL)
+	for _, accountID := range accountIDs {
+		if p.nodes[accountID] == nil {
+			p.nodes[accountID] = make(map[string]time.Time)
+		}
+		p.nodes[accountID][nodeID] = expiresAt
+	}
+	return nil
+}
+
+func (p *MemoryPresence) Offline(ctx context.Context, nodeID string, accountID uint) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.nodes[accountID], nodeID)
+	if len(p.nodes[accountID]) == 0 {
+		delete(p.nodes, accountID)
+	}
+	return nil
+}
+
+func (p *MemoryPresence) Nodes(ctx context.Context, accountID uint) ([]string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := time.Now()
+	nodes := make([]string, 0, len(p.nodes[accountID]))
+	for nodeID, expiresAt := range p.nodes[accountID] {
+		if expiresAt.After(now) {
+			nodes = append(nodes, nodeID)
+		}
+	}
+	return nodes, nil
+}
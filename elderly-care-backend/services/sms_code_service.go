@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/factories"
+	"elderly-care-backend/config"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrSmsCodeInvalid   = errors.New(constants.SMS_CODE_INVALID)
+	ErrSmsTooFrequent   = errors.New(constants.SMS_TOO_FREQUENT)
+	ErrSmsLimitExceeded = errors.New(constants.SMS_LIMIT_EXCEEDED)
+	ErrSmsSend          = errors.New(constants.SMS_SEND_ERROR)
+)
+
+// 各用途的短信文案
+var smsTemplates = map[string]string{
+	constants.SMS_PURPOSE_LOGIN:        "您的登录验证码为%s，%d分钟内有效，请勿告诉他人。",
+	constants.SMS_PURPOSE_REGISTER:     "您正在注册账号，验证码为%s，%d分钟内有效。",
+	constants.SMS_PURPOSE_VERIFY_PHONE: "您正在验证手机号，验证码为%s，%d分钟内有效。",
+	constants.SMS_PURPOSE_RESET:        "您正在找回密码，验证码为%s，%d分钟内有效，如非本人操作请忽略。",
+}
+
+// 校验验证码：次数用完或校验成功后删除，返回 1 成功、0 错误、-1 不存在或已失效
+var verifySmsCodeScript = redis.NewScript(`
+local code = redis.call('HGET', KEYS[1], 'code')
+if not code then
+	return -1
+end
+local attempts = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+if code == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+	return 1
+end
+if attempts >= tonumber(ARGV[2]) then
+	redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+type smsSettings struct {
+	codeTTL        time.Duration
+	resendInterval time.Duration
+	phoneDailyMax  int64
+	ipHourlyMax    int64
+	maxAttempts    int
+}
+
+// SmsCodeService 短信验证码的发送、频率限制和校验
+type SmsCodeService struct {
+	redisClient *redis.Client
+}
+
+func NewSmsCodeService(redisClient *redis.Client) *SmsCodeService {
+	return &SmsCodeService{redisClient: redisClient}
+}
+
+// IsValidSmsPurpose 判断验证码用途是否支持
+func IsValidSmsPurpose(purpose string) bool {
+	_, ok := smsTemplates[purpose]
+	return ok
+}
+
+// SendCode 生成并发送验证码，同一手机号和IP都有发送频率限制
+func (s *SmsCodeService) SendCode(ctx context.Context, phone, purpose, ip string) error {
+	template, ok := smsTemplates[purpose]
+	if !ok {
+		return ErrSmsCodeInvalid
+	}
+	settings := loadSmsSettings()
+
+	// 重发间隔内不允许再次发送
+	acquired, err := s.redisClient.SetNX(ctx, constants.SMS_COOLDOWN_PREFIX+phone, 1, settings.resendInterval).Result()
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return ErrSmsTooFrequent
+	}
+
+	dailyKey := constants.SMS_PHONE_DAILY_PREFIX + phone + ":" + time.Now().Format("20060102")
+	ipKey := constants.SMS_IP_HOURLY_PREFIX + ip + ":" + time.Now().Format("2006010215")
+	var dailyCount, ipCount *redis.IntCmd
+	if _, err = s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		dailyCount = pipe.Incr(ctx, dailyKey)
+		pipe.Expire(ctx, dailyKey, 24*time.Hour)
+		ipCount = pipe.Incr(ctx, ipKey)
+		pipe.Expire(ctx, ipKey, time.Hour)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if dailyCount.Val() > settings.phoneDailyMax || ipCount.Val() > settings.ipHourlyMax {
+		return ErrSmsLimitExceeded
+	}
+
+	code, err := generateSmsCode()
+	if err != nil {
+		return err
+	}
+	codeKey := smsCodeKey(purpose, phone)
+	if _, err = s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, codeKey)
+		pipe.HSet(ctx, codeKey, "code", code, "attempts", 0)
+		pipe.Expire(ctx, codeKey, settings.codeTTL)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf(template, code, int(settings.codeTTL.Minutes()))
+	if signName := config.Config.Sms.SignName; signName != "" {
+		content = "【" + signName + "】" + content
+	}
+	if err = factories.SmsSenderFactory.GetSmsSender().Send(phone, content); err != nil {
+		// 发送失败时清除验证码和重发间隔，允许用户立即重试
+		s.redisClient.Del(ctx, codeKey, constants.SMS_COOLDOWN_PREFIX+phone)
+		return ErrSmsSend
+	}
+	return nil
+}
+
+// VerifyCode 校验验证码，成功后验证码立即失效，错误次数过多时验证码作废
+func (s *SmsCodeService) VerifyCode(ctx context.Context, phone, purpose, code string) error {
+	if code == "" {
+		return ErrSmsCodeInvalid
+	}
+	settings := loadSmsSettings()
+	// 计数、比较和删除在脚本中原子执行，并发校验时同一验证码只能成功一次
+	result, err := verifySmsCodeScript.Run(ctx, s.redisClient,
+		[]string{smsCodeKey(purpose, phone)}, code, settings.maxAttempts).Int()
+	if err != nil {
+		return err
+	}
+	if result != 1 {
+		return ErrSmsCodeInvalid
+	}
+	return nil
+}
+
+func smsCodeKey(purpose, phone string) string {
+	return constants.SMS_CODE_PREFIX + purpose + ":" + phone
+}
+
+// 生成6位数字验证码
+func generateSmsCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func loadSmsSettings() smsSettings {
+	sms := config.Config.Sms
+	withDefault := func(value, defaultValue int) int {
+		if value <= 0 {
+			return defaultValue
+		}
+		return value
+	}
+	return smsSettings{
+		codeTTL:        time.Duration(withDefault(sms.CodeTTL, constants.SMS_DEFAULT_CODE_TTL)) * time.Second,
+		resendInterval: time.Duration(withDefault(sms.ResendInterval, constants.SMS_DEFAULT_RESEND_INTERVAL)) * time.Second,
+		phoneDailyMax:  int64(withDefault(sms.PhoneDailyMax, constants.SMS_DEFAULT_PHONE_DAILY_MAX)),
+		ipHourlyMax:    int64(withDefault(sms.IPHourlyMax, constants.SMS_DEFAULT_IP_HOURLY_MAX)),
+		maxAttempts:    withDefault(sms.MaxAttempts, constants.SMS_DEFAULT_MAX_ATTEMPTS),
+	}
+}
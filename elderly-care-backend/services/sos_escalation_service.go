@@ -0,0 +1,210 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// EscalationStage 一个升级阶段：扩大匹配半径、通知更多志愿者，并再等待一段时间
+type EscalationStage struct {
+	Radius  float64       // 匹配半径(米)
+	Limit   int           // 最多通知的志愿者数量
+	Timeout time.Duration // 本阶段等待响应的时间
+}
+
+// 默认升级阶段，全部阶段都无人响应后标记为 escalated 交由人工跟进
+var DefaultEscalationStages = []EscalationStage{
+	{Radius: 5000, Limit: 20, Timeout: 3 * time.Minute},
+	{Radius: 10000, Limit: 50, Timeout: 3 * time.Minute},
+}
+
+// SOSNotifier 向志愿者发出SOS通知
+type SOSNotifier interface {
+	NotifyVolunteers(record *models.SOSRecord, volunteers []map[string]interface{})
+}
+
+// 默认通知实现，只记录日志
+type logSOSNotifier struct{}
+
+func (logSOSNotifier) NotifyVolunteers(record *models.SOSRecord, volunteers []map[string]interface{}) {
+	global.Logger.Info("sos notify volunteers",
+		zap.Uint("sos_id", record.ID),
+		zap.Int("count", len(volunteers)))
+}
+
+type SOSEscalationService struct {
+	db              *gorm.DB
+	matchingService *TaskMatchingService
+	notifier        SOSNotifier
+	stages          []EscalationStage
+}
+
+func NewSOSEscalationService(db *gorm.DB, matchingService *TaskMatchingService) *SOSEscalationService {
+	return &SOSEscalationService{
+		db:              db,
+		matchingService: matchingService,
+		notifier:        logSOSNotifier{},
+		stages:          DefaultEscalationStages,
+	}
+}
+
+// SetNotifier 替换通知实现
+func (s *SOSEscalationService) SetNotifier(notifier SOSNotifier) {
+	s.notifier = notifier
+}
+
+// Start 定时扫描超时的SOS记录并升级
+func (s *SOSEscalationService) Start() {
+	ticker := time.NewTicker(constants.SOS_ESCALATION_SCAN_INTERVAL)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.scan()
+	}
+}
+
+func (s *SOSEscalationService) scan() {
+	// 多实例部署时只允许一个实例执行本轮扫描
+	mutex := global.LockManager.NewMutex(constants.LOCK_SOS_ESCALATION,
+		redsync.WithExpiry(constants.SOS_ESCALATION_SCAN_INTERVAL),
+		redsync.WithTries(1))
+	if err := mutex.Lock(); err != nil {
+		return
+	}
+	defer mutex.Unlock()
+
+	var records []models.SOSRecord
+	if err := s.db.Where("status IN (?) AND timeout_at IS NOT NULL AND timeout_at <= ?",
+		[]string{constants.SOS_STATUS_PENDING, constants.SOS_STATUS_MATCHING}, time.Now()).
+		Find(&records).Error; err != nil {
+		global.Logger.Error("scan overdue sos error", zap.Error(err))
+		return
+	}
+
+	for i := range records {
+		if err := s.Escalate(&records[i]); err != nil {
+			global.Logger.Error("escalate sos error", zap.Uint("sos_id", records[i].ID), zap.Error(err))
+		}
+	}
+}
+
+// Escalate 将一条超时的SOS推进到下一个升级阶段
+func (s *SOSEscalationService) Escalate(record *models.SOSRecord) error {
+	var stage int64
+	if err := s.db.Model(&models.SOSEscalationLog{}).Where("sos_id = ?", record.ID).Count(&stage).Error; err != nil {
+		return err
+	}
+
+	// 所有阶段都已用完，交由人工跟进
+	if int(stage) >= len(s.stages) {
+		_, err := s.transition(record, nil, &models.SOSEscalationLog{
+			SOSID:    record.ID,
+			Stage:    int(stage) + 1,
+			ToStatus: constants.SOS_STATUS_ESCALATED,
+			Note:     "所有升级阶段均无人响应，转人工跟进",
+		})
+		return err
+	}
+
+	current := s.stages[stage]
+	volunteers, err := s.matchingService.MatchEmergencyVolunteersWithin(record.UserID,
+		record.Latitude, record.Longitude, current.Radius, current.Limit)
+	if err != nil {
+		return fmt.Errorf("匹配志愿者失败: %v", err)
+	}
+
+	timeoutAt := time.Now().Add(current.Timeout)
+	escalationLog := &models.SOSEscalationLog{
+		SOSID:         record.ID,
+		Stage:         int(stage) + 1,
+		ToStatus:      constants.SOS_STATUS_MATCHING,
+		Radius:        current.Radius,
+		NotifiedCount: len(volunteers),
+		NotifiedIDs:   joinVolunteerIDs(volunteers),
+		Note:          fmt.Sprintf("扩大匹配半径至%.0f米", current.Radius),
+	}
+	applied, err := s.transition(record, &timeoutAt, escalationLog)
+	if err != nil || !applied {
+		return err
+	}
+
+	s.notifier.NotifyVolunteers(record, volunteers)
+	return nil
+}
+
+// transition 在状态未被其他请求修改的前提下更新SOS状态并写入升级记录
+func (s *SOSEscalationService) transition(record *models.SOSRecord, timeoutAt *time.Time, escalationLog *models.SOSEscalationLog) (bool, error) {
+	escalationLog.FromStatus = record.Status
+	applied := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.SOSRecord{}).
+			Where("id = ? AND status = ?", record.ID, record.Status).
+			Updates(map[string]interface{}{
+				"status":     escalationLog.ToStatus,
+				"timeout_at": timeoutAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		// 期间已被接受或解决，不再升级
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Create(escalationLog).Error; err != nil {
+			return err
+		}
+		record.Status = escalationLog.ToStatus
+		record.TimeoutAt = timeoutAt
+		applied = true
+		return nil
+	})
+	return applied, err
+}
+
+// GetEscalationLogs 获取SOS的升级记录
+func (s *SOSEscalationService) GetEscalationLogs(sosID uint) ([]models.SOSEscalationLog, error) {
+	var logs []models.SOSEscalationLog
+	err := s.db.Where("sos_id = ?", sosID).Order("stage ASC").Find(&logs).Error
+	return logs, err
+}
+
+// VolunteerID 从单条匹配结果中取出志愿者ID
+func VolunteerID(volunteer map[string]interface{}) (uint, bool) {
+	switch id := volunteer["id"].(type) {
+	case int64:
+		return uint(id), true
+	case uint64:
+		return uint(id), true
+	case uint:
+		return id, true
+	}
+	return 0, false
+}
+
+// VolunteerIDs 从匹配结果中取出志愿者ID
+func VolunteerIDs(volunteers []map[string]interface{}) []uint {
+	ids := make([]uint, 0, len(volunteers))
+	for _, volunteer := range volunteers {
+		if id, ok := VolunteerID(volunteer); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func joinVolunteerIDs(volunteers []map[string]interface{}) string {
+	ids := VolunteerIDs(volunteers)
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(strs, ",")
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// TaskAccessService 任务和SOS的访问权限：发布人（求助人）本人、接单人、发布人已确认的监护人和管理员可以查看和处理，
+// 运营人员只能查看
+type TaskAccessService struct {
+	db              *gorm.DB
+	guardianService *GuardianService
+}
+
+func NewTaskAccessService(db *gorm.DB) *TaskAccessService {
+	return &TaskAccessService{db: db, guardianService: NewGuardianService(db)}
+}
+
+// CanView 能否查看任务或SOS的详情和记录
+func (s *TaskAccessService) CanView(accountID uint, role string, ownerID uint, assigneeID *uint) (bool, error) {
+	if role == constants.ROLE_OPERATOR {
+		return true, nil
+	}
+	return s.CanHandle(accountID, role, ownerID, assigneeID)
+}
+
+// CanHandle 能否处理任务或SOS
+func (s *TaskAccessService) CanHandle(accountID uint, role string, ownerID uint, assigneeID *uint) (bool, error) {
+	if role == constants.ROLE_ADMIN || accountID == ownerID || (assigneeID != nil && *assigneeID == accountID) {
+		return true, nil
+	}
+	return s.guardianService.IsGuardian(accountID, ownerID)
+}
+
+// SOSAssignee SOS关联任务的接单人，还没有人接单时为空
+func (s *TaskAccessService) SOSAssignee(sosRecord *models.SOSRecord) (*uint, error) {
+	task := &models.Task{}
+	err := s.db.Select("id", "assignee_id").Take(task, sosRecord.TaskID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return task.AssigneeID, err
+}
+
+// CanActFor 能否以 ownerID 的身份发布任务或求助：本人，或老人已确认的监护人代为发布
+func (s *TaskAccessService) CanActFor(accountID, ownerID uint) (bool, error) {
+	if accountID == ownerID {
+		return true, nil
+	}
+	return s.guardianService.IsGuardian(accountID, ownerID)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/global"
+	"elderly-care-backend/models"
+	"elderly-care-backend/vo"
+	"errors"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var errDeadlineExtended = errors.New("deadline extended")
+
+// EventPusher 向指定用户推送实时事件
+type EventPusher interface {
+	PushEvent(accountID uint, eventType string, data interface{})
+}
+
+type TaskExpiryService struct {
+	db               *gorm.DB
+	lifecycleService *TaskLifecycleService
+	pusher           EventPusher
+}
+
+func NewTaskExpiryService(db *gorm.DB, lifecycleService *TaskLifecycleService, pusher EventPusher) *TaskExpiryService {
+	return &TaskExpiryService{
+		db:               db,
+		lifecycleService: lifecycleService,
+		pusher:           pusher,
+	}
+}
+
+// Start 定时将超过截止时间仍无人接单的任务标记为过期
+func (s *TaskExpiryService) Start() {
+	ticker := time.NewTicker(constants.TASK_EXPIRY_SCAN_INTERVAL)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.scan()
+	}
+}
+
+func (s *TaskExpiryService) scan() {
+	// 多实例部署时只允许一个实例执行本轮扫描
+	mutex := global.LockManager.NewMutex(constants.LOCK_TASK_EXPIRY,
+		redsync.WithExpiry(constants.TASK_EXPIRY_SCAN_INTERVAL),
+		redsync.WithTries(1))
+	if err := mutex.Lock(); err != nil {
+		return
+	}
+	defer mutex.Unlock()
+
+	var tasks []models.Task
+	if err := s.db.Where("status = ? AND deadline IS NOT NULL AND deadline <= ?",
+		constants.TASK_STATUS_PENDING, time.Now()).
+		Find(&tasks).Error; err != nil {
+		global.Logger.Error("scan overdue task error", zap.Error(err))
+		return
+	}
+
+	for _, task := range tasks {
+		expired, err := s.lifecycleService.Transition(task.ID, TaskTransition{
+			To:     constants.TASK_STATUS_EXPIRED,
+			Reason: "超过截止时间无人接单",
+			// 扫描之后可能被延期，以数据库中的截止时间为准
+			Guard: func(current *models.Task) error {
+				if current.Deadline == nil || current.Deadline.After(time.Now()) {
+					return errDeadlineExtended
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			if !errors.Is(err, errDeadlineExtended) {
+				global.Logger.Warn("expire task error", zap.Uint("task_id", task.ID), zap.Error(err))
+			}
+			continue
+		}
+
+		s.pusher.PushEvent(expired.CreatorID, constants.WS_EVENT_TASK_EXPIRED, vo.TaskStatusEventVO{
+			TaskID:   expired.ID,
+			Title:    expired.Title,
+			Status:   expired.Status,
+			Deadline: expired.Deadline,
+		})
+	}
+}
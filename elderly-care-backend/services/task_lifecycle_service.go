@@ -0,0 +1,165 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/server_error"
+	"elderly-care-backend/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// 任务状态机：每个状态允许流转到的下一状态，completed/cancelled/expired 为终态
+var taskTransitions = map[string][]string{
+	constants.TASK_STATUS_PENDING: {
+		constants.TASK_STATUS_ASSIGNED,
+		constants.TASK_STATUS_CANCELLED,
+		constants.TASK_STATUS_EXPIRED,
+	},
+	constants.TASK_STATUS_ASSIGNED: {
+		constants.TASK_STATUS_IN_PROGRESS,
+		constants.TASK_STATUS_ASSIGNED, // 改派给其他志愿者
+		constants.TASK_STATUS_PENDING,  // 志愿者放弃
+		constants.TASK_STATUS_CANCELLED,
+	},
+	constants.TASK_STATUS_IN_PROGRESS: {
+		constants.TASK_STATUS_COMPLETED,
+		constants.TASK_STATUS_ASSIGNED,
+		constants.TASK_STATUS_PENDING,
+		constants.TASK_STATUS_CANCELLED,
+	},
+}
+
+// CanTransitionTask 判断任务状态能否从 from 流转到 to
+func CanTransitionTask(from, to string) bool {
+	for _, next := range taskTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskTransition 一次任务状态流转
+type TaskTransition struct {
+	To         string
+	OperatorID uint                          // 操作人，系统自动流转时为0
+	Reason     string                        // 写入状态变更记录
+	Updates    map[string]interface{}        // 需要和状态一起更新的字段
+	Guard      func(task *models.Task) error // 额外的业务校验，如只有接单人可以开始任务
+}
+
+type TaskLifecycleService struct {
+	db *gorm.DB
+}
+
+func NewTaskLifecycleService(db *gorm.DB) *TaskLifecycleService {
+	return &TaskLifecycleService{db: db}
+}
+
+// Transition 校验并执行任务状态流转，同时写入状态变更记录
+func (s *TaskLifecycleService) Transition(taskID uint, transition TaskTransition) (*models.Task, error) {
+	var task *models.Task
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = s.TransitionInTx(tx, taskID, transition)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// TransitionInTx 在调用方的事务中执行状态流转，用于和其他表的更新保持原子性
+func (s *TaskLifecycleService) TransitionInTx(tx *gorm.DB, taskID uint, transition TaskTransition) (*models.Task, error) {
+	var task models.Task
+	if err := tx.First(&task, taskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, server_error.TaskNotExistError
+		}
+		return nil, err
+	}
+	if !CanTransitionTask(task.Status, transition.To) {
+		return nil, server_error.TaskInvalidTransitionError
+	}
+	if transition.Guard != nil {
+		if err := transition.Guard(&task); err != nil {
+			return nil, err
+		}
+	}
+
+	updates := map[string]interface{}{"status": transition.To}
+	for k, v := range transition.Updates {
+		updates[k] = v
+	}
+	// 以读取时的状态为条件更新，并发请求中只有一个能命中，其余的影响行数为0
+	result := tx.Model(&models.Task{}).
+		Where("id = ? AND status = ?", task.ID, task.Status).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, server_error.TaskInvalidTransitionError
+	}
+
+	if err := tx.Create(&models.TaskStatusHistory{
+		TaskID:     task.ID,
+		FromStatus: task.Status,
+		ToStatus:   transition.To,
+		OperatorID: transition.OperatorID,
+		Reason:     transition.Reason,
+	}).Error; err != nil {
+		return nil, err
+	}
+	// 返回更新后的任务，Updates 中的字段(如接单人)也要反映出来
+	if err := tx.First(&task, task.ID).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// GetHistory 获取任务的状态变更记录
+func (s *TaskLifecycleService) GetHistory(taskID uint) ([]models.TaskStatusHistory, error) {
+	var history []models.TaskStatusHistory
+	err := s.db.Where("task_id = ?", taskID).Order("id ASC").Find(&history).Error
+	return history, err
+}
+
+// RequireAssignee 只允许当前接单人操作
+func RequireAssignee(operatorID uint) func(task *models.Task) error {
+	return func(task *models.Task) error {
+		if task.AssigneeID == nil || *task.AssigneeID != operatorID {
+			return server_error.TaskNotAssigneeError
+		}
+		return nil
+	}
+}
+
+// RequireCreator 只允许任务发布人操作
+func RequireCreator(operatorID uint) func(task *models.Task) error {
+	return func(task *models.Task) error {
+		if task.CreatorID != operatorID {
+			return server_error.TaskNotCreatorError
+		}
+		return nil
+	}
+}
+
+// AcceptTransition 志愿者接单。改派也是 assigned→assigned，需要在事务内再确认任务仍待接单，
+// 否则并发接单时排在后面的请求会把任务改派给自己
+func AcceptTransition(volunteerID uint, reason string) TaskTransition {
+	return TaskTransition{
+		To:         constants.TASK_STATUS_ASSIGNED,
+		OperatorID: volunteerID,
+		Reason:     reason,
+		Updates:    map[string]interface{}{"assignee_id": volunteerID},
+		Guard: func(task *models.Task) error {
+			if task.Status != constants.TASK_STATUS_PENDING {
+				return server_error.TaskInvalidTransitionError
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/server_error"
+	"elderly-care-backend/models"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 测试库：设置了 TEST_MYSQL_DSN 时使用MySQL，否则使用临时的SQLite文件
+func openTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	config := &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true,
+	}
+	var dialector gorm.Dialector
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		dialector = mysql.Open(dsn)
+	} else {
+		// SQLite同一时间只允许一个写事务，BEGIN IMMEDIATE 让并发事务排队而不是直接报 database is locked
+		dsn = filepath.Join(t.TempDir(), "test.db") + "?_txlock=immediate&_pragma=busy_timeout(10000)"
+		dialector = sqlite.Open(dsn)
+	}
+	db, err := gorm.Open(dialector, config)
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	if err = db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestTransitionConcurrentAccept(t *testing.T) {
+	db := openTestDB(t, &models.Task{}, &models.TaskStatusHistory{})
+	task := &models.Task{
+		CreatorID:   1,
+		Title:       "买菜",
+		Description: "帮忙买菜",
+		Status:      constants.TASK_STATUS_PENDING,
+		Address:     "测试地址",
+	}
+	if err := db.Create(task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("task_id = ?", task.ID).Delete(&models.TaskStatusHistory{})
+		db.Delete(task)
+	})
+
+	const volunteers = 50
+	service := NewTaskLifecycleService(db)
+	errs := make([]error, volunteers)
+	accepted := make([]*models.Task, volunteers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < volunteers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			volunteerID := uint(i + 2)
+			<-start
+			accepted[i], errs[i] = service.Transition(task.ID, AcceptTransition(volunteerID, "志愿者接单"))
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	var winner uint
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if winner != 0 {
+				t.Fatalf("volunteer %d and %d both accepted the task", winner, i+2)
+			}
+			winner = uint(i + 2)
+			// 返回的是更新后的任务，接单人已经写入
+			if accepted[i].AssigneeID == nil || *accepted[i].AssigneeID != winner {
+				t.Errorf("returned assignee = %v, want %d", accepted[i].AssigneeID, winner)
+			}
+		case !errors.Is(err, server_error.TaskInvalidTransitionError):
+			t.Errorf("volunteer %d: unexpected error %v", i+2, err)
+		}
+	}
+	if winner == 0 {
+		t.Fatal("no volunteer accepted the task")
+	}
+
+	var saved models.Task
+	if err := db.First(&saved, task.ID).Error; err != nil {
+		t.Fatalf("load task: %v", err)
+	}
+	if saved.Status != constants.TASK_STATUS_ASSIGNED || saved.AssigneeID == nil || *saved.AssigneeID != winner {
+		t.Errorf("task = %s/%v, want assigned to %d", saved.Status, saved.AssigneeID, winner)
+	}
+	var history int64
+	db.Model(&models.TaskStatusHistory{}).Where("task_id = ?", task.ID).Count(&history)
+	if history != 1 {
+		t.Errorf("status history rows = %d, want 1", history)
+	}
+}
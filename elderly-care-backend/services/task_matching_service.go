@@ -1,13 +1,18 @@
 package services
 
 import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/config"
 	"elderly-care-backend/global"
 	"elderly-care-backend/models"
 	"fmt"
-	"math"
+	"sort"
+	"time"
 )
 
-type TaskMatchingService struct{}
+type TaskMatchingService struct {
+	Scorer MatchScorer // 为空时使用 config.yaml 中配置的加权打分
+}
 
 // MatchVolunteersForTask 为任务匹配志愿者
 func (s *TaskMatchingService) MatchVolunteersForTask(taskID uint) ([]map[string]interface{}, error) {
@@ -16,56 +21,142 @@ func (s *TaskMatchingService) MatchVolunteersForTask(taskID uint) ([]map[string]
 		return nil, fmt.Errorf("获取任务失败: %v", err)
 	}
 
-	// 使用GORM查询附近志愿者
+	maxDistance := float64(defaultMatchMaxDistance)
+	if config.Config != nil && config.Config.Matching.MaxDistance > 0 {
+		maxDistance = config.Config.Matching.MaxDistance
+	}
+
+	// 查询附近此刻可接单的志愿者
 	var volunteers []map[string]interface{}
 	query := `
         SELECT 
-            id, nickname, latitude, longitude, address,
-            (6371000 * acos(cos(radians(?)) * cos(radians(latitude)) * 
-            cos(radians(longitude) - radians(?)) + sin(radians(?)) * 
-            sin(radians(latitude)))) as distance
-        FROM account 
-        WHERE id != ? AND distance < ?
+            a.id, a.nickname, a.latitude, a.longitude, a.address, a.last_location_update,
+            (6371000 * acos(cos(radians(?)) * cos(radians(a.latitude)) * 
+            cos(radians(a.longitude) - radians(?)) + sin(radians(?)) * 
+            sin(radians(a.latitude)))) as distance
+        FROM account a
+        WHERE a.id != ? AND ` + eligibleVolunteerCondition + `
+        HAVING distance < ?
         ORDER BY distance ASC
         LIMIT 20`
 
-	if err := global.Db.Raw(query, task.Latitude, task.Longitude, task.Latitude, task.CreatorID, 5000).Scan(&volunteers).Error; err != nil {
+	args := []interface{}{task.Latitude, task.Longitude, task.Latitude, task.CreatorID}
+	args = append(args, eligibleVolunteerArgs(time.Now())...)
+	args = append(args, maxDistance)
+	if err := global.Db.Raw(query, args...).Scan(&volunteers).Error; err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.loadCandidates(&task, volunteers)
+	if err != nil {
 		return nil, err
 	}
 
-	// 计算匹配分数
-	for _, volunteer := range volunteers {
-		score := s.calculateMatchScore(volunteer)
+	// 计算匹配分数，附带各因子得分用于解释排序
+	scorer := s.scorer()
+	for i, volunteer := range volunteers {
+		score, breakdown := scorer.Score(&task, candidates[i])
 		volunteer["match_score"] = score
+		volunteer["score_breakdown"] = breakdown
 	}
+	sort.SliceStable(volunteers, func(i, j int) bool {
+		return volunteers[i]["match_score"].(float64) > volunteers[j]["match_score"].(float64)
+	})
 
 	return volunteers, nil
 }
 
-// MatchEmergencyVolunteers 紧急情况匹配
-func (s *TaskMatchingService) MatchEmergencyVolunteers(taskID uint, lat, lng float64) ([]map[string]interface{}, error) {
+func (s *TaskMatchingService) scorer() MatchScorer {
+	if s.Scorer == nil {
+		return NewConfiguredMatchScorer()
+	}
+	return s.Scorer
+}
+
+// 批量加载志愿者的评价、在手任务和同类任务经验
+func (s *TaskMatchingService) loadCandidates(task *models.Task, volunteers []map[string]interface{}) ([]*MatchCandidate, error) {
+	candidates := make([]*MatchCandidate, len(volunteers))
+	byID := make(map[uint]*MatchCandidate, len(volunteers))
+	ids := make([]uint, 0, len(volunteers))
+	for i, volunteer := range volunteers {
+		candidate := &MatchCandidate{}
+		candidate.AccountID, _ = VolunteerID(volunteer)
+		candidate.Distance, _ = volunteer["distance"].(float64)
+		if lastUpdate, ok := volunteer["last_location_update"].(time.Time); ok {
+			candidate.LastLocationUpdate = &lastUpdate
+		}
+		candidates[i] = candidate
+		byID[candidate.AccountID] = candidate
+		ids = append(ids, candidate.AccountID)
+	}
+	if len(ids) == 0 {
+		return candidates, nil
+	}
+
+	var evaluations []models.AccountEvaluation
+	if err := global.Db.Where("account_id IN ?", ids).Find(&evaluations).Error; err != nil {
+		return nil, err
+	}
+	for _, evaluation := range evaluations {
+		byID[evaluation.AccountID].AverageScore = evaluation.AverageScore
+		byID[evaluation.AccountID].RatingCount = evaluation.AssignCount
+	}
+
+	type taskCount struct {
+		AssigneeID uint
+		Count      int
+	}
+	var openCounts []taskCount
+	if err := global.Db.Model(&models.Task{}).
+		Select("assignee_id, COUNT(*) AS count").
+		Where("assignee_id IN ? AND status IN ?", ids, []string{constants.TASK_STATUS_ASSIGNED, constants.TASK_STATUS_IN_PROGRESS}).
+		Group("assignee_id").Scan(&openCounts).Error; err != nil {
+		return nil, err
+	}
+	for _, count := range openCounts {
+		byID[count.AssigneeID].OpenTasks = count.Count
+	}
+
+	var categoryCounts []taskCount
+	if err := global.Db.Model(&models.Task{}).
+		Select("assignee_id, COUNT(*) AS count").
+		Where("assignee_id IN ? AND status = ? AND category = ?", ids, constants.TASK_STATUS_COMPLETED, task.Category).
+		Group("assignee_id").Scan(&categoryCounts).Error; err != nil {
+		return nil, err
+	}
+	for _, count := range categoryCounts {
+		byID[count.AssigneeID].CategoryCompleted = count.Count
+	}
+
+	return candidates, nil
+}
+
+// MatchEmergencyVolunteers 紧急情况匹配，requesterID 为求助人，不参与匹配
+func (s *TaskMatchingService) MatchEmergencyVolunteers(requesterID uint, lat, lng float64) ([]map[string]interface{}, error) {
+	return s.MatchEmergencyVolunteersWithin(requesterID, lat, lng, 3000, 10)
+}
+
+// MatchEmergencyVolunteersWithin 在指定半径内匹配紧急志愿者，SOS超时升级时用于逐级扩大范围
+func (s *TaskMatchingService) MatchEmergencyVolunteersWithin(requesterID uint, lat, lng, radius float64, limit int) ([]map[string]interface{}, error) {
 	query := `
         SELECT 
-            id, nickname, latitude, longitude, address,
-            (6371000 * acos(cos(radians(?)) * cos(radians(latitude)) * 
-            cos(radians(longitude) - radians(?)) + sin(radians(?)) * 
-            sin(radians(latitude)))) as distance
-        FROM account 
-        WHERE id != ? AND distance < 3000
+            a.id, a.nickname, a.latitude, a.longitude, a.address,
+            (6371000 * acos(cos(radians(?)) * cos(radians(a.latitude)) * 
+            cos(radians(a.longitude) - radians(?)) + sin(radians(?)) * 
+            sin(radians(a.latitude)))) as distance
+        FROM account a
+        WHERE a.id != ? AND ` + eligibleVolunteerCondition + `
+        HAVING distance < ?
         ORDER BY distance ASC
-        LIMIT 10`
+        LIMIT ?`
 
+	args := []interface{}{lat, lng, lat, requesterID}
+	args = append(args, eligibleVolunteerArgs(time.Now())...)
+	args = append(args, radius, limit)
 	var volunteers []map[string]interface{}
-	if err := global.Db.Raw(query, lat, lng, lat, taskID).Scan(&volunteers).Error; err != nil {
+	if err := global.Db.Raw(query, args...).Scan(&volunteers).Error; err != nil {
 		return nil, err
 	}
 
 	return volunteers, nil
 }
-
-func (s *TaskMatchingService) calculateMatchScore(volunteer map[string]interface{}) float64 {
-	distance := volunteer["distance"].(float64)
-	// 距离越近分数越高
-	distanceScore := math.Max(0, 100-(distance/5000)*100)
-	return distanceScore
-}
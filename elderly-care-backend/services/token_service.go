@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/config"
+	"elderly-care-backend/dto/account_dto"
+	"elderly-care-backend/models"
+	"elderly-care-backend/utils"
+	"elderly-care-backend/vo"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New(constants.REFRESH_TOKEN_INVALID)
+	ErrTokenRevoked        = errors.New(constants.TOKEN_REVOKED)
+	ErrAccountSuspended    = errors.New(constants.ACCOUNT_SUSPENDED)
+)
+
+// TokenService 签发访问/刷新token对，并基于Redis维护token版本号和吊销列表
+type TokenService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+func NewTokenService(db *gorm.DB, redisClient *redis.Client) *TokenService {
+	return &TokenService{db: db, redisClient: redisClient}
+}
+
+// IssueTokens 为账号签发新的token对，刷新token登记到Redis，轮换或退出时删除
+func (s *TokenService) IssueTokens(ctx context.Context, account *models.Account) (*vo.TokenVO, error) {
+	version, err := s.tokenVersion(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtConfig := config.Config.Jwt
+	accessToken, _, err := s.genToken(account, version, constants.TOKEN_SUBJECT_ACCESS, jwtConfig.Expire)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refreshID, err := s.genToken(account, version, constants.TOKEN_SUBJECT_REFRESH, jwtConfig.RefreshExpire)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.redisClient.Set(ctx, constants.ACCOUNT_REFRESH_TOKEN_PREFIX+refreshID, account.ID,
+		time.Duration(jwtConfig.RefreshExpire)*time.Second).Err(); err != nil {
+		return nil, err
+	}
+
+	return &vo.TokenVO{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        jwtConfig.Expire,
+		RefreshExpiresIn: jwtConfig.RefreshExpire,
+	}, nil
+}
+
+func (s *TokenService) genToken(account *models.Account, version int64, subject string, expire int) (string, string, error) {
+	now := time.Now()
+	id := uuid.NewString()
+	token, err := utils.GenToken(&account_dto.Claims{
+		AccountId:    account.ID,
+		Nickname:     account.Nickname,
+		Role:         account.Role,
+		TokenVersion: version,
+		StandardClaims: jwt.StandardClaims{
+			Id:        id,
+			Issuer:    "elder",
+			Subject:   subject,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(time.Duration(expire) * time.Second).Unix(),
+		},
+	}, config.Config.Jwt.SecretKey)
+	return token, id, err
+}
+
+// Refresh 使用刷新token换取新的token对，旧的刷新token立即作废
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*vo.TokenVO, error) {
+	claims, err := utils.ParseToken(refreshToken, config.Config.Jwt.SecretKey)
+	if err != nil || claims.Subject != constants.TOKEN_SUBJECT_REFRESH || claims.Id == "" {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	version, err := s.tokenVersion(ctx, claims.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenVersion != version {
+		return nil, ErrTokenRevoked
+	}
+
+	// 删除成功才说明该刷新token未被使用过，并发刷新时只有一个请求能成功
+	deleted, err := s.redisClient.Del(ctx, constants.ACCOUNT_REFRESH_TOKEN_PREFIX+claims.Id).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		// 已作废的刷新token被再次使用，可能已经泄露，吊销该账号的所有会话
+		if err = s.RevokeAll(ctx, claims.AccountId); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// 重新读取账号，角色变更和暂停在刷新时生效
+	account := &models.Account{}
+	if err = s.db.Take(account, claims.AccountId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	if account.Status == constants.ACCOUNT_STATUS_SUSPENDED {
+		return nil, ErrAccountSuspended
+	}
+	return s.IssueTokens(ctx, account)
+}
+
+// CheckAccess 校验访问token未被吊销，供鉴权中间件使用
+func (s *TokenService) CheckAccess(ctx context.Context, claims *account_dto.Claims) error {
+	if claims.Subject != constants.TOKEN_SUBJECT_ACCESS {
+		return ErrTokenRevoked
+	}
+
+	pipe := s.redisClient.Pipeline()
+	versionCmd := pipe.Get(ctx, tokenVersionKey(claims.AccountId))
+	revokedCmd := pipe.Exists(ctx, constants.ACCOUNT_REVOKED_TOKEN_PREFIX+claims.Id)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	version, err := versionCmd.Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if claims.TokenVersion != version || (claims.Id != "" && revokedCmd.Val() > 0) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// Revoke 吊销单个访问token，吊销记录保留到token过期为止
+func (s *TokenService) Revoke(ctx context.Context, claims *account_dto.Claims) error {
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if claims.Id == "" || ttl <= 0 {
+		return nil
+	}
+	return s.redisClient.Set(ctx, constants.ACCOUNT_REVOKED_TOKEN_PREFIX+claims.Id, 1, ttl).Err()
+}
+
+// RevokeRefreshToken 作废属于该账号的刷新token，token无效时忽略
+func (s *TokenService) RevokeRefreshToken(ctx context.Context, accountID uint, refreshToken string) error {
+	claims, err := utils.ParseToken(refreshToken, config.Config.Jwt.SecretKey)
+	if err != nil || claims.Subject != constants.TOKEN_SUBJECT_REFRESH || claims.AccountId != accountID {
+		return nil
+	}
+	return s.redisClient.Del(ctx, constants.ACCOUNT_REFRESH_TOKEN_PREFIX+claims.Id).Err()
+}
+
+// RevokeAll 递增token版本号，该账号已签发的访问token和刷新token全部失效
+func (s *TokenService) RevokeAll(ctx context.Context, accountID uint) error {
+	return s.redisClient.Incr(ctx, tokenVersionKey(accountID)).Err()
+}
+
+func (s *TokenService) tokenVersion(ctx context.Context, accountID uint) (int64, error) {
+	version, err := s.redisClient.Get(ctx, tokenVersionKey(accountID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return version, err
+}
+
+func tokenVersionKey(accountID uint) string {
+	return constants.ACCOUNT_TOKEN_VERSION_PREFIX + strconv.FormatUint(uint64(accountID), 10)
+}
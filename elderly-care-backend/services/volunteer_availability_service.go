@@ -0,0 +1,125 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/dto"
+	"elderly-care-backend/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrAvailabilityWindow = errors.New(constants.AVAILABILITY_WINDOW_ERROR)
+
+// 可接单志愿者的筛选条件，a 为 account 表别名：
+// 角色为志愿者且账号未被暂停、已通过实名认证、处于在岗状态、当前时间落在某个服务时段内（未配置时段视为全天）、进行中的任务未达上限
+const eligibleVolunteerCondition = `
+        a.role = ? AND a.status = ?
+        AND EXISTS (
+            SELECT 1 FROM volunteer_verification vv
+            WHERE vv.account_id = a.id AND vv.status = ?
+        )
+        AND EXISTS (
+            SELECT 1 FROM volunteer_availability va
+            WHERE va.account_id = a.id AND va.on_duty = TRUE
+            AND (SELECT COUNT(*) FROM task t WHERE t.assignee_id = a.id AND t.status IN (?, ?)) < va.max_concurrent_tasks
+        )
+        AND (
+            NOT EXISTS (SELECT 1 FROM availability_window w WHERE w.account_id = a.id)
+            OR EXISTS (
+                SELECT 1 FROM availability_window w
+                WHERE w.account_id = a.id
+                AND w.weekday = ? AND w.start_time <= ? AND w.end_time > ?
+            )
+        )`
+
+// eligibleVolunteerArgs 与 eligibleVolunteerCondition 中占位符一一对应
+func eligibleVolunteerArgs(now time.Time) []interface{} {
+	clock := now.Format("15:04")
+	return []interface{}{
+		constants.ROLE_VOLUNTEER, constants.ACCOUNT_STATUS_ACTIVE,
+		constants.VERIFICATION_STATUS_VERIFIED,
+		constants.TASK_STATUS_ASSIGNED, constants.TASK_STATUS_IN_PROGRESS,
+		int(now.Weekday()), clock, clock,
+	}
+}
+
+type VolunteerAvailabilityService struct {
+	db *gorm.DB
+}
+
+func NewVolunteerAvailabilityService(db *gorm.DB) *VolunteerAvailabilityService {
+	return &VolunteerAvailabilityService{db: db}
+}
+
+// GetAvailability 获取志愿者的接单状态，没有记录时返回默认值（下岗）
+func (s *VolunteerAvailabilityService) GetAvailability(accountID uint) (*models.VolunteerAvailability, []models.AvailabilityWindow, error) {
+	availability := &models.VolunteerAvailability{AccountID: accountID, MaxConcurrentTasks: 3}
+	if err := s.db.Where("account_id = ?", accountID).Take(availability).Error; err != nil &&
+		!errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	var windows []models.AvailabilityWindow
+	if err := s.db.Where("account_id = ?", accountID).
+		Order("weekday ASC, start_time ASC").
+		Find(&windows).Error; err != nil {
+		return nil, nil, err
+	}
+	return availability, windows, nil
+}
+
+// SetOnDuty 切换在岗/下岗状态
+func (s *VolunteerAvailabilityService) SetOnDuty(accountID uint, onDuty bool) error {
+	return s.db.Where(models.VolunteerAvailability{AccountID: accountID}).
+		Assign(map[string]interface{}{"on_duty": onDuty}).
+		FirstOrCreate(&models.VolunteerAvailability{}).Error
+}
+
+// UpdateAvailability 更新任务上限，并用新的时段整体替换原有时段
+func (s *VolunteerAvailabilityService) UpdateAvailability(accountID uint, req *dto.AvailabilityRequest) error {
+	windows := make([]models.AvailabilityWindow, 0, len(req.Windows))
+	for _, w := range req.Windows {
+		start, err := time.Parse("15:04", w.StartTime)
+		if err != nil {
+			return ErrAvailabilityWindow
+		}
+		end, err := time.Parse("15:04", w.EndTime)
+		if err != nil || !end.After(start) {
+			return ErrAvailabilityWindow
+		}
+		windows = append(windows, models.AvailabilityWindow{
+			AccountID: accountID,
+			Weekday:   w.Weekday,
+			StartTime: start.Format("15:04"),
+			EndTime:   end.Format("15:04"),
+		})
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(models.VolunteerAvailability{AccountID: accountID}).
+			Assign(map[string]interface{}{"max_concurrent_tasks": req.MaxConcurrentTasks}).
+			FirstOrCreate(&models.VolunteerAvailability{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("account_id = ?", accountID).
+			Delete(&models.AvailabilityWindow{}).Error; err != nil {
+			return err
+		}
+		if len(windows) == 0 {
+			return nil
+		}
+		return tx.Create(&windows).Error
+	})
+}
+
+// CountOpenTasks 统计志愿者已接单未完成的任务数
+func (s *VolunteerAvailabilityService) CountOpenTasks(accountID uint) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.Task{}).
+		Where("assignee_id = ? AND status IN ?", accountID,
+			[]string{constants.TASK_STATUS_ASSIGNED, constants.TASK_STATUS_IN_PROGRESS}).
+		Count(&count).Error
+	return count, err
+}
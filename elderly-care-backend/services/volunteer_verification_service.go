@@ -0,0 +1,150 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/common/server_error"
+	"elderly-care-backend/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 认证状态机：提交材料进入待审核，审核通过后可被暂停，暂停后可恢复
+var verificationTransitions = map[string][]string{
+	"": {constants.VERIFICATION_STATUS_PENDING},
+	constants.VERIFICATION_STATUS_PENDING: {
+		constants.VERIFICATION_STATUS_PENDING, // 审核前重新提交
+		constants.VERIFICATION_STATUS_VERIFIED,
+		constants.VERIFICATION_STATUS_REJECTED,
+	},
+	constants.VERIFICATION_STATUS_REJECTED:  {constants.VERIFICATION_STATUS_PENDING},
+	constants.VERIFICATION_STATUS_VERIFIED:  {constants.VERIFICATION_STATUS_SUSPENDED},
+	constants.VERIFICATION_STATUS_SUSPENDED: {constants.VERIFICATION_STATUS_VERIFIED},
+}
+
+func canTransitionVerification(from, to string) bool {
+	for _, next := range verificationTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+type VolunteerVerificationService struct {
+	db *gorm.DB
+}
+
+func NewVolunteerVerificationService(db *gorm.DB) *VolunteerVerificationService {
+	return &VolunteerVerificationService{db: db}
+}
+
+// GetVerification 获取志愿者的认证记录
+func (s *VolunteerVerificationService) GetVerification(accountID uint) (*models.VolunteerVerification, error) {
+	verification := &models.VolunteerVerification{}
+	if err := s.db.Where("account_id = ?", accountID).Take(verification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, server_error.VerificationNotExistError
+		}
+		return nil, err
+	}
+	return verification, nil
+}
+
+// IsVerified 志愿者是否已通过认证
+func (s *VolunteerVerificationService) IsVerified(accountID uint) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.VolunteerVerification{}).
+		Where("account_id = ? AND status = ?", accountID, constants.VERIFICATION_STATUS_VERIFIED).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Submit 提交或重新提交认证材料，进入待审核状态
+func (s *VolunteerVerificationService) Submit(accountID uint, realName, frontObject, backObject string) (*models.VolunteerVerification, error) {
+	verification, err := s.GetVerification(accountID)
+	if err != nil && !errors.Is(err, server_error.VerificationNotExistError) {
+		return nil, err
+	}
+	if verification == nil {
+		verification = &models.VolunteerVerification{AccountID: accountID}
+	}
+	if !canTransitionVerification(verification.Status, constants.VERIFICATION_STATUS_PENDING) {
+		return nil, server_error.VerificationInvalidTransitionError
+	}
+
+	verification.RealName = realName
+	verification.IDFrontObject = frontObject
+	verification.IDBackObject = backObject
+	verification.Status = constants.VERIFICATION_STATUS_PENDING
+	verification.Remark = ""
+	verification.ReviewerID = nil
+	verification.ReviewedAt = nil
+	verification.SubmittedAt = time.Now()
+	if err := s.db.Save(verification).Error; err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
+
+// Review 管理员审核：通过、驳回、暂停或恢复认证
+func (s *VolunteerVerificationService) Review(accountID, reviewerID uint, to, remark string) (*models.VolunteerVerification, error) {
+	verification, err := s.GetVerification(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if to == constants.VERIFICATION_STATUS_PENDING || !canTransitionVerification(verification.Status, to) {
+		return nil, server_error.VerificationInvalidTransitionError
+	}
+
+	now := time.Now()
+	// 以读取时的状态为条件更新，避免两名管理员同时审核互相覆盖
+	result := s.db.Model(&models.VolunteerVerification{}).
+		Where("id = ? AND status = ?", verification.ID, verification.Status).
+		Updates(map[string]interface{}{
+			"status":      to,
+			"remark":      remark,
+			"reviewer_id": reviewerID,
+			"reviewed_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, server_error.VerificationInvalidTransitionError
+	}
+
+	// 暂停认证的志愿者同时下岗，不再参与匹配
+	if to == constants.VERIFICATION_STATUS_SUSPENDED {
+		if err := s.db.Model(&models.VolunteerAvailability{}).
+			Where("account_id = ?", accountID).
+			Update("on_duty", false).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	verification.Status = to
+	verification.Remark = remark
+	verification.ReviewerID = &reviewerID
+	verification.ReviewedAt = &now
+	return verification, nil
+}
+
+// ListVerifications 按状态分页查询认证记录，status 为空时查询全部
+func (s *VolunteerVerificationService) ListVerifications(status string, page, pageSize int) ([]models.VolunteerVerification, int64, error) {
+	query := s.db.Model(&models.VolunteerVerification{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var verifications []models.VolunteerVerification
+	err := query.Order("submitted_at ASC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&verifications).Error
+	return verifications, total, err
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"elderly-care-backend/common/constants"
+	"elderly-care-backend/models"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const ErrTopicInvalid = FrameError(constants.TOPIC_INVALID)
+
+// TopicPublisher 向订阅了主题的连接发布事件
+type TopicPublisher interface {
+	Publish(topic, eventType string, data interface{})
+}
+
+func UserTopic(accountID uint) string {
+	return fmt.Sprintf("%s:%d", constants.WS_TOPIC_USER, accountID)
+}
+
+func TaskTopic(taskID uint) string {
+	return fmt.Sprintf("%s:%d", constants.WS_TOPIC_TASK, taskID)
+}
+
+func SOSTopic(sosID uint) string {
+	return fmt.Sprintf("%s:%d", constants.WS_TOPIC_SOS, sosID)
+}
+
+func NavigationTopic(sessionID uint) string {
+	return fmt.Sprintf("%s:%d", constants.WS_TOPIC_NAVIGATION, sessionID)
+}
+
+// 解析 <类型>:<ID> 格式的主题
+func parseTopic(topic string) (string, uint, error) {
+	kind, idStr, found := strings.Cut(topic, ":")
+	if !found {
+		return "", 0, ErrTopicInvalid
+	}
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil || id == 0 {
+		return "", 0, ErrTopicInvalid
+	}
+	return kind, uint(id), nil
+}
+
+// TopicAuthorizer 校验用户能否订阅主题
+type TopicAuthorizer struct {
+	db *gorm.DB
+}
+
+func NewTopicAuthorizer(db *gorm.DB) *TopicAuthorizer {
+	return &TopicAuthorizer{db: db}
+}
+
+// CanSubscribe 个人主题只能订阅自己的；任务和SOS主题限发布人、接单人和发布人的监护人；导航主题限会话双方
+func (a *TopicAuthorizer) CanSubscribe(accountID uint, topic string) (bool, error) {
+	kind, id, err := parseTopic(topic)
+	if err != nil {
+		return false, err
+	}
+
+	switch kind {
+	case constants.WS_TOPIC_USER:
+		return id == accountID, nil
+	case constants.WS_TOPIC_TASK:
+		task := &models.Task{}
+		if err = a.db.Select("id", "creator_id", "assignee_id").Take(task, id).Error; err != nil {
+			return false, ignoreNotFound(err)
+		}
+		return a.isParticipant(accountID, task.CreatorID, task.AssigneeID)
+	case constants.WS_TOPIC_SOS:
+		sosRecord := &models.SOSRecord{}
+		if err = a.db.Select("id", "user_id", "task_id").Take(sosRecord, id).Error; err != nil {
+			return false, ignoreNotFound(err)
+		}
+		task := &models.Task{}
+		if err = a.db.Select("id", "assignee_id").Take(task, sosRecord.TaskID).Error; err != nil &&
+			!errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err
+		}
+		return a.isParticipant(accountID, sosRecord.UserID, task.AssigneeID)
+	case constants.WS_TOPIC_NAVIGATION:
+		session := &models.NavigationSession{}
+		if err = a.db.Select("id", "helper_id", "elderly_id").Take(session, id).Error; err != nil {
+			return false, ignoreNotFound(err)
+		}
+		return accountID == session.HelperID || accountID == session.ElderlyID, nil
+	default:
+		return false, ErrTopicInvalid
+	}
+}
+
+// 发布人本人、接单人或发布人已确认的监护人
+func (a *TopicAuthorizer) isParticipant(accountID, ownerID uint, assigneeID *uint) (bool, error) {
+	if accountID == ownerID || (assigneeID != nil && *assigneeID == accountID) {
+		return true, nil
+	}
+	var count int64
+	err := a.db.Model(&models.Guardian{}).
+		Where("elder_id = ? AND guardian_id = ? AND status = ?", ownerID, accountID, constants.GUARDIAN_STATUS_VERIFIED).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// 主题对应的记录不存在时按无权限处理
+func ignoreNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	return err
+}
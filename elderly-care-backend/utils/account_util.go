@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"elderly-care-backend/common/constants"
 	"elderly-care-backend/common/server_error"
 	"elderly-care-backend/dto/account_dto"
 	"fmt"
+	"strings"
+	"unicode"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
@@ -20,6 +23,26 @@ func CheckPasswordHash(password, hashPassword string) bool {
 	return err == nil
 }
 
+// CheckPasswordStrength 密码长度8-32位，需同时包含字母和数字，且不能包含手机号
+func CheckPasswordStrength(password, phone string) bool {
+	if len(password) < constants.PASSWORD_MIN_LENGTH || len(password) > constants.PASSWORD_MAX_LENGTH {
+		return false
+	}
+	if phone != "" && strings.Contains(password, phone) {
+		return false
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	return hasLetter && hasDigit
+}
+
 func GenToken(claims jwt.Claims, secretKey string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secretKey))
@@ -52,6 +75,12 @@ func SetAccountIdInContext(c *gin.Context, accountId uint) {
 func SetNickNameInContext(c *gin.Context, nickName string) {
 	c.Set("nickname", nickName)
 }
+func SetClaimsInContext(c *gin.Context, claims *account_dto.Claims) {
+	c.Set("claims", claims)
+}
+func SetRoleInContext(c *gin.Context, role string) {
+	c.Set("role", role)
+}
 
 func GetAccountIdInContext(c *gin.Context) uint {
 
@@ -63,6 +92,26 @@ func GetAccountIdInContext(c *gin.Context) uint {
 	return value.(uint)
 }
 
+func GetClaimsInContext(c *gin.Context) *account_dto.Claims {
+
+	value, exists := c.Get("claims")
+
+	if !exists {
+		return nil
+	}
+	return value.(*account_dto.Claims)
+}
+
+func GetRoleInContext(c *gin.Context) string {
+
+	value, exists := c.Get("role")
+
+	if !exists {
+		return ""
+	}
+	return value.(string)
+}
+
 func GetNickNameInContext(c *gin.Context) string {
 
 	value, exists := c.Get("nickname")
@@ -0,0 +1,22 @@
+package utils
+
+import "time"
+
+// 前端可能传入的时间格式
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParseTime 按常用格式解析时间，不带时区的按本地时间处理
+func ParseTime(s string) (time.Time, error) {
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
@@ -1,6 +1,12 @@
 package vo
 
 type AccountEvaluationVo struct {
-	Score       float64 `json:"score"` // 总得分
-	AssignCount int     // 获取任务数量
+	Score        float64 `json:"score"` // 总得分
+	AssignCount  int     // 获取任务数量
+	AverageScore float64 `json:"average_score"` // 平均分
+	Star1Count   int     `json:"star1_count"`   // 评分分布
+	Star2Count   int     `json:"star2_count"`
+	Star3Count   int     `json:"star3_count"`
+	Star4Count   int     `json:"star4_count"`
+	Star5Count   int     `json:"star5_count"`
 }
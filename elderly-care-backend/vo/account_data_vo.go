@@ -0,0 +1,42 @@
+package vo
+
+import (
+	"elderly-care-backend/models"
+	"time"
+)
+
+// AccountExportVO 个人数据导出，包含与账号相关的全部记录
+type AccountExportVO struct {
+	ExportedAt    time.Time                     `json:"exported_at"`
+	Account       models.Account                `json:"account"`
+	Guardians     []models.Guardian             `json:"guardians"` // 作为老人或监护人的关系
+	CreatedTasks  []models.Task                 `json:"created_tasks"`
+	AssignedTasks []models.Task                 `json:"assigned_tasks"`
+	Evaluations   []models.TaskEvaluation       `json:"evaluations"` // 给出和收到的评价
+	SOSRecords    []models.SOSRecord            `json:"sos_records"`
+	Locations     []models.UserLocation         `json:"locations"`
+	Messages      []models.Message              `json:"messages"` // 发出和收到的聊天消息
+	Contacts      []models.ContactList          `json:"contacts"`
+	LoginAttempts []models.LoginAttempt         `json:"login_attempts"`
+	Availability  *models.VolunteerAvailability `json:"availability,omitempty"`
+	Windows       []models.AvailabilityWindow   `json:"availability_windows"`
+	Verification  *models.VolunteerVerification `json:"verification,omitempty"`
+	Media         []ExportMediaVO               `json:"media"`
+}
+
+// ExportMediaVO 导出数据中引用的文件，私有文件为临时链接
+type ExportMediaVO struct {
+	Source    string     `json:"source"` // avatar, message, id_front, id_back
+	SourceID  uint       `json:"source_id,omitempty"`
+	Url       string     `json:"url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type AccountDeletionVO struct {
+	Status      string     `json:"status"`
+	Reason      string     `json:"reason"`
+	RequestedAt time.Time  `json:"requested_at"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
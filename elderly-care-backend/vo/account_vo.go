@@ -13,7 +13,7 @@ type AccountVO struct {
 	Phone       string           `gorm:"size:32;uniqueIndex" json:"phone"` // 手机号作为登录账号
 	Password    string           `gorm:"size:64" json:"-"`
 	Age         int              `json:"age"`
-	Role        string           `gorm:"size:20;default:'user'" json:"role"` // 用户角色: user, volunteer, admin
+	Role        string           `gorm:"size:20;default:'elderly'" json:"role"` // 用户角色: elderly, volunteer, guardian, admin, operator
 	// 新增位置相关字段
 	Latitude           float64    `gorm:"type:decimal(10,8)" json:"latitude"`
 	Longitude          float64    `gorm:"type:decimal(11,8)" json:"longitude"`
@@ -0,0 +1,38 @@
+package vo
+
+import (
+	"elderly-care-backend/models"
+	"time"
+)
+
+// ChatGroupVO 我的群聊列表，包含我在群里的角色、状态和未读数
+type ChatGroupVO struct {
+	ID                  uint               `json:"id"`
+	Name                string             `json:"name"`
+	OwnerID             uint               `json:"owner_id"`
+	TaskID              *uint              `json:"task_id,omitempty"`
+	Role                string             `json:"role"`
+	Status              string             `json:"status"` // invited 表示还没有接受邀请
+	UnreadCount         int                `json:"unread_count"`
+	LastMessageID       uint               `json:"last_message_id"`
+	LastMessageFrom     uint               `json:"last_message_from"`
+	LastMessageType     models.MessageType `json:"last_message_type"`
+	LastMessagePreview  string             `json:"last_message_preview"`
+	LastMessageRecalled bool               `json:"last_message_recalled"`
+	LastChatTime        *time.Time         `json:"last_chat_time,omitempty"`
+}
+
+type ChatGroupMemberVO struct {
+	AccountID uint       `json:"account_id"`
+	Nickname  string     `json:"nickname"`
+	Avatar    string     `json:"avatar"`
+	Role      string     `json:"role"`
+	Status    string     `json:"status"`
+	InvitedBy uint       `json:"invited_by"`
+	JoinedAt  *time.Time `json:"joined_at,omitempty"`
+}
+
+type ChatGroupDetailVO struct {
+	models.ChatGroup
+	Members []ChatGroupMemberVO `json:"members"`
+}
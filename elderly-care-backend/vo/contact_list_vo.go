@@ -1,10 +1,35 @@
 package vo
 
-import "time"
+import (
+	"elderly-care-backend/models"
+	"time"
+)
 
 type ContactListVo struct {
-	ContactID    uint      `json:"contact_id"`
-	Nickname     string    `json:"nickname"`
-	Avatar       string    `json:"avatar"`
-	LastChatTime time.Time `json:"last_chat_time"`
+	ContactID           uint               `json:"contact_id"`
+	Nickname            string             `json:"nickname"`
+	Avatar              string             `json:"avatar"`
+	LastChatTime        time.Time          `json:"last_chat_time"`
+	UnreadCount         int                `json:"unread_count"`
+	LastMessageID       uint               `json:"last_message_id"`
+	LastMessageFrom     uint               `json:"last_message_from"`
+	LastMessageType     models.MessageType `json:"last_message_type"`
+	LastMessagePreview  string             `json:"last_message_preview"` // 文本截取前几个字，图片、文件和语音显示为占位文字
+	LastMessageRecalled bool               `json:"last_message_recalled"`
+}
+
+// ChatReceiptVO 回执：From 发给 To 的消息中 ID 不超过 MessageID 的都已达到 Status；
+// 群聊回执 GroupID 不为 0，表示 To 在群内的确认进度，From 为 0
+type ChatReceiptVO struct {
+	GroupID   uint                 `json:"group_id,omitempty"`
+	From      uint                 `json:"from"`
+	To        uint                 `json:"to"`
+	MessageID uint                 `json:"message_id"`
+	Status    models.MessageStatus `json:"status"`
+}
+
+// ChatVoiceVO 语音上传结果，发送语音消息时 Content 填写 Url
+type ChatVoiceVO struct {
+	Url      string `json:"url"`
+	Duration int    `json:"duration"` // 时长(秒)
 }
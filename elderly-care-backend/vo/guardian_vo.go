@@ -0,0 +1,17 @@
+package vo
+
+import "time"
+
+type GuardianVO struct {
+	ID             uint       `json:"id"`
+	ElderID        uint       `json:"elder_id"`
+	GuardianID     uint       `json:"guardian_id"`
+	Nickname       string     `json:"nickname"` // 对方昵称
+	Avatar         string     `json:"avatar"`
+	Phone          string     `json:"phone"`
+	Relationship   string     `json:"relationship"`
+	Priority       int        `json:"priority"`
+	Status         string     `json:"status"`
+	ElderConsentAt time.Time  `json:"elder_consent_at"`
+	VerifiedAt     *time.Time `json:"verified_at"`
+}
@@ -19,3 +19,9 @@ func Fail(msg string) *ResponseVO {
 		Msg:     msg,
 	}
 }
+
+// 分页查询结果
+type PageVO struct {
+	List  interface{} `json:"list"`
+	Total int64       `json:"total"`
+}
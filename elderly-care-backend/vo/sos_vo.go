@@ -16,6 +16,29 @@ type SOSRecordVO struct {
 	Timeout     int       `json:"timeout,omitempty"`
 }
 
+// SOSAlertVO 推送给志愿者的SOS告警
+type SOSAlertVO struct {
+	SOSID       uint       `json:"sos_id"`
+	TaskID      uint       `json:"task_id"`
+	UserID      uint       `json:"user_id"`
+	Latitude    float64    `json:"latitude"`
+	Longitude   float64    `json:"longitude"`
+	Address     string     `json:"address"`
+	Description string     `json:"description"`
+	Severity    string     `json:"severity"`
+	Distance    float64    `json:"distance"`
+	TimeoutAt   *time.Time `json:"timeout_at,omitempty"`
+}
+
+// SOSStatusEventVO 推送给求助者的SOS状态变更
+type SOSStatusEventVO struct {
+	SOSID       uint   `json:"sos_id"`
+	TaskID      uint   `json:"task_id"`
+	Status      string `json:"status"`
+	VolunteerID uint   `json:"volunteer_id,omitempty"`
+	Nickname    string `json:"nickname,omitempty"`
+}
+
 type SOSResponseVO struct {
 	SOSID   uint                     `json:"sos_id"`
 	TaskID  uint                     `json:"task_id"`
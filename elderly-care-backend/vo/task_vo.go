@@ -3,20 +3,29 @@ package vo
 import "time"
 
 type TaskVO struct {
-	ID          uint      `json:"id"`
-	CreatorID   uint      `json:"creator_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Category    string    `json:"category"`
-	Status      string    `json:"status"`
-	Reward      float64   `json:"reward"`
-	Latitude    float64   `json:"latitude"`
-	Longitude   float64   `json:"longitude"`
-	Address     string    `json:"address"`
-	Distance    float64   `json:"distance,omitempty"`
-	IsEmergency bool      `json:"is_emergency,omitempty"`
-	CreatorName string    `json:"creator_name,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uint       `json:"id"`
+	CreatorID   uint       `json:"creator_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Category    string     `json:"category"`
+	Status      string     `json:"status"`
+	Reward      float64    `json:"reward"`
+	Latitude    float64    `json:"latitude"`
+	Longitude   float64    `json:"longitude"`
+	Address     string     `json:"address"`
+	Distance    float64    `json:"distance,omitempty"`
+	IsEmergency bool       `json:"is_emergency,omitempty"`
+	CreatorName string     `json:"creator_name,omitempty"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TaskStatusEventVO 推送给任务相关用户的状态变更
+type TaskStatusEventVO struct {
+	TaskID   uint       `json:"task_id"`
+	Title    string     `json:"title"`
+	Status   string     `json:"status"`
+	Deadline *time.Time `json:"deadline,omitempty"`
 }
 
 type TaskMatchVO struct {
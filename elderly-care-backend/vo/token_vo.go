@@ -0,0 +1,17 @@
+package vo
+
+type TokenVO struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`         // 访问token有效期(秒)
+	RefreshExpiresIn int    `json:"refresh_expires_in"` // 刷新token有效期(秒)
+}
+
+type LoginBlockedVO struct {
+	RetryAfter int `json:"retry_after"` // 需要等待的秒数
+}
+
+type ResetTokenVO struct {
+	ResetToken string `json:"reset_token"`
+	ExpiresIn  int    `json:"expires_in"` // 有效期(秒)
+}
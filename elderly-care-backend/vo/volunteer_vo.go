@@ -0,0 +1,21 @@
+package vo
+
+import (
+	"elderly-care-backend/models"
+	"time"
+)
+
+type AvailabilityVO struct {
+	OnDuty             bool                        `json:"on_duty"`
+	MaxConcurrentTasks int                         `json:"max_concurrent_tasks"`
+	OpenTasks          int                         `json:"open_tasks"` // 当前进行中的任务数
+	Windows            []models.AvailabilityWindow `json:"windows"`
+}
+
+type VerificationVO struct {
+	Status      string     `json:"status"` // 未提交时为空
+	RealName    string     `json:"real_name"`
+	Remark      string     `json:"remark"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+	ReviewedAt  *time.Time `json:"reviewed_at"`
+}
@@ -0,0 +1,14 @@
+package vo
+
+import "time"
+
+// WsEventVO 实时网关发给客户端的帧，type 为业务通道(chat, location, sos, task, navigation)或控制帧类型
+type WsEventVO struct {
+	Type  string      `json:"type"`
+	Event string      `json:"event,omitempty"` // 具体事件，如 sos_alert
+	ID    string      `json:"id,omitempty"`    // 对客户端帧的回复中带回客户端的帧ID
+	Topic string      `json:"topic,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+	Time  time.Time   `json:"time"`
+}